@@ -11,6 +11,7 @@ require (
 	github.com/mattn/go-zglob v0.0.6
 	github.com/mcuadros/go-version v0.0.0-20190830083331-035f6764e8d2
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -21,43 +21,66 @@ const (
 
 // Fields is the list of possible root fields for a build file
 var Fields = []string{"doc", "default", "extends", "repository", "context", "singleton",
-	"shell", "properties", "configuration", "expose", "environment", "dotenv", "targets", "version"}
+	"shell", "properties", "configuration", "expose", "environment", "dotenv", "targets", "version",
+	"integrity", "scaffold", "secrets", "safe", "declarations", "language"}
 
 // Build structure
 type Build struct {
-	File        string
-	Dir         string
-	Here        string
-	Default     []string
-	Doc         string
-	Repository  string
-	Singleton   string
-	Shell       map[string][]string
-	Scripts     []string
-	Extends     []string
-	Config      []string
-	Expose      []string
-	Properties  util.Object
-	Environment map[string]string
-	DotEnv      []string
-	Targets     map[string]*Target
-	Parents     []*Build
-	Root        *Build
-	Version     string
-	Template    bool
+	File         string
+	Dir          string
+	Here         string
+	Default      []string
+	Doc          string
+	Repository   string
+	Singleton    string
+	Shell        map[string][]string
+	Scripts      []string
+	Extends      []string
+	Config       []string
+	Expose       []string
+	Properties   util.Object
+	Environment  map[string]string
+	DotEnv       []string
+	Targets      map[string]*Target
+	Parents      []*Build
+	Root         *Build
+	Version      string
+	Template     bool
+	Integrity    map[string]string
+	Scaffold     *Scaffold
+	Secrets      map[string]Secret
+	Safe         bool
+	SafePackages []string
+	Declarations map[string]Declaration
+	Origins      map[string][]Origin
+	Language     string
 }
 
 // NewBuild creates a Build from a build file.
-// This is the public entry point. It initializes a fresh visited map
-// and delegates the actual work to newBuildInternal.
+// This is the public entry point. It initializes a fresh visited map, loads
+// lock file in base directory and delegates the actual work to
+// newBuildInternal.
 func NewBuild(file, base, repo string, template bool) (*Build, error) {
 	visited := make(map[string]bool)
-	return newBuildInternal(file, base, repo, template, visited)
+	integrity, err := LoadLockFile(base)
+	if err != nil {
+		return nil, fmt.Errorf("loading lock file: %v", err)
+	}
+	build, err := newBuildInternal(file, base, repo, template, visited, integrity)
+	if err != nil {
+		return nil, err
+	}
+	if err := build.checkLanguage(build.GetLanguage()); err != nil {
+		return nil, err
+	}
+	return build, nil
 }
 
 // newBuildInternal performs the real build creation while tracking visited files
-// to detect cyclic dependencies.
-func newBuildInternal(file, base, repo string, template bool, visited map[string]bool) (*Build, error) {
+// to detect cyclic dependencies. Checksums inherited from child build files
+// overwrite those defined in this build file.
+func newBuildInternal(file, base, repo string, template bool, visited map[string]bool,
+	integrity map[string]string) (*Build, error) {
 	// Resolve absolute path for cycle detection
 	absPath, err := filepath.Abs(file)
 	if err != nil {
@@ -88,12 +111,16 @@ func newBuildInternal(file, base, repo string, template bool, visited map[string
 	if err := ParseFields(object, build, repo); err != nil {
 		return nil, err
 	}
+	for name, checksum := range integrity {
+		build.Integrity[name] = checksum
+	}
 	// Resolve parents using the same visited map
 	build.Parents, err = build.getParentsInternal(visited)
 	if err != nil {
 		return nil, err
 	}
 	build.Properties = build.GetProperties()
+	build.mergeOrigins()
 	build.Environment = build.GetEnvironment()
 	build.DotEnv = build.GetDotEnv()
 	build.SetDir(build.Dir)
@@ -147,6 +174,24 @@ func ParseFields(object util.Object, build *Build, repo string) error {
 	if err := ParseTargets(object, build); err != nil {
 		return err
 	}
+	if err := ParseIntegrity(object, build); err != nil {
+		return err
+	}
+	if err := ParseScaffold(object, build); err != nil {
+		return err
+	}
+	if err := ParseSecrets(object, build); err != nil {
+		return err
+	}
+	if err := ParseSafe(object, build); err != nil {
+		return err
+	}
+	if err := ParseDeclarations(object, build); err != nil {
+		return err
+	}
+	if err := ParseLanguage(object, build); err != nil {
+		return err
+	}
 	return ParseVersion(object, build)
 }
 
@@ -197,22 +242,75 @@ func (build *Build) SetRoot(root *Build) {
 	}
 }
 
+// DefaultPropertyPrefix is the default prefix of environment variables that
+// define properties
+const DefaultPropertyPrefix = "NEON_PROP_"
+
+// PropertyPrefix is the prefix of environment variables that define properties
+var PropertyPrefix = DefaultPropertyPrefix
+
 // SetCommandLineProperties defines properties passed on command line in the
 // context. These properties overwrite those define in the build file.
+// Properties in environment variables starting with PropertyPrefix are set
+// first, then those in file and finally those in props. String values of
+// declared properties are converted to their type.
 // - props: properties as a YAML map
+// - file: YAML or JSON file with properties (ignored if empty)
 // Return: error if something went wrong
-func (build *Build) SetCommandLineProperties(props string) error {
+func (build *Build) SetCommandLineProperties(props, file string) error {
+	declarations := build.GetDeclarations()
+	for name, value := range EnvironmentProperties(os.Environ()) {
+		value = declarations[name].Convert(value)
+		build.Properties[name] = value
+		build.AddOrigin(name, Origin{Value: value, File: PropertyPrefix + name, Source: "environment"})
+	}
+	if file != "" {
+		source, err := util.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading properties file: %v", err)
+		}
+		var object util.Object
+		if err := yaml.Unmarshal(source, &object); err != nil {
+			return fmt.Errorf("parsing properties file '%s': properties must be a map with string keys", file)
+		}
+		for name, value := range object {
+			value = declarations[name].Convert(value)
+			build.Properties[name] = value
+			build.AddOrigin(name, Origin{Value: value, File: file, Source: "props-file"})
+		}
+	}
 	var object util.Object
 	err := yaml.Unmarshal([]byte(props), &object)
 	if err != nil {
 		return fmt.Errorf("parsing command line properties: properties must be a map with string keys")
 	}
 	for name, value := range object {
+		value = declarations[name].Convert(value)
 		build.Properties[name] = value
+		build.AddOrigin(name, Origin{Value: value, Source: "props"})
 	}
 	return nil
 }
 
+// EnvironmentProperties returns properties defined in environment with
+// variables starting with PropertyPrefix. Values are strings.
+// - environ: the environment as a list of NAME=VALUE strings
+// Return: properties by name
+func EnvironmentProperties(environ []string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, line := range environ {
+		index := strings.Index(line, "=")
+		if index < 0 || !strings.HasPrefix(line[:index], PropertyPrefix) {
+			continue
+		}
+		name := line[len(PropertyPrefix):index]
+		if name != "" {
+			properties[name] = line[index+1:]
+		}
+	}
+	return properties
+}
+
 // GetParents returns parent build objects.
 // Return list of build objects and an error if any.
 func (build *Build) GetParents() ([]*Build, error) {
@@ -233,7 +331,10 @@ func (build *Build) getParentsInternal(visited map[string]bool) ([]*Build, error
 		if err != nil {
 			return nil, fmt.Errorf("searching parent build file '%s': %v", extend, err)
 		}
-		parent, err := newBuildInternal(file, filepath.Dir(file), build.Repository, build.Template, visited)
+		if err := build.VerifyIntegrity(extend, file); err != nil {
+			return nil, fmt.Errorf("verifying parent build file '%s': %v", extend, err)
+		}
+		parent, err := newBuildInternal(file, filepath.Dir(file), build.Repository, build.Template, visited, build.Integrity)
 		if err != nil {
 			return nil, fmt.Errorf("loading parent build file '%s': %v", extend, err)
 		}
@@ -370,12 +471,16 @@ func (build *Build) GetParentTarget(name string) (*Target, error) {
 // - context: the context to run into
 // - targets: targets to run as a slice of strings
 // Return: error if something went wrong
-func (build *Build) Run(context *Context, targets []string) error {
+func (build *Build) Run(context *Context, targets []string) (err error) {
 	if err := build.CheckVersion(context); err != nil {
 		return err
 	}
+	defer func() {
+		if finalErr := context.RunFinalizers(nil); finalErr != nil && err == nil {
+			err = finalErr
+		}
+	}()
 	var listener net.Listener
-	var err error
 	if listener, err = build.EnsureSingle(context); err != nil {
 		return err
 	}
@@ -60,3 +60,40 @@ func TestGetShell(t *testing.T) {
 	}
 	Assert(shell, []string{"foo"}, t)
 }
+
+func TestEnvironmentProperties(t *testing.T) {
+	environ := []string{"PATH=/bin", "NEON_PROP_version=1.2", "NEON_PROP_=empty", "NEON_PROP_url=http://host?a=b"}
+	Assert(EnvironmentProperties(environ), map[string]interface{}{
+		"version": "1.2",
+		"url":     "http://host?a=b",
+	}, t)
+}
+
+func TestSetCommandLineProperties(t *testing.T) {
+	dir := "/tmp/neon"
+	file, err := WriteFile(dir, "props.yml", `{"FOO": "file", "BAR": "file", "LIST": [1, 2]}`)
+	if err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	_ = os.Setenv("NEON_PROP_FOO", "env")
+	_ = os.Setenv("NEON_PROP_SPAM", "env")
+	defer func() {
+		_ = os.Unsetenv("NEON_PROP_FOO")
+		_ = os.Unsetenv("NEON_PROP_SPAM")
+	}()
+	build := &Build{Properties: map[string]interface{}{"FOO": "build", "EGGS": "build"}}
+	if err := build.SetCommandLineProperties("{BAR: props}", file); err != nil {
+		t.Fatalf("setting properties: %v", err)
+	}
+	Assert(build.Properties["EGGS"], "build", t)
+	Assert(build.Properties["SPAM"], "env", t)
+	Assert(build.Properties["FOO"], "file", t)
+	Assert(build.Properties["BAR"], "props", t)
+	Assert(build.Properties["LIST"], []interface{}{1, 2}, t)
+	if err := build.SetCommandLineProperties("", dir+"/missing.yml"); err == nil {
+		t.Errorf("missing properties file should fail")
+	}
+}
@@ -6,9 +6,10 @@ import (
 
 // BuiltinDesc is a descriptor for a builtin function
 type BuiltinDesc struct {
-	Name string
-	Func interface{}
-	Help string
+	Name   string
+	Func   interface{}
+	Help   string
+	Unsafe bool
 }
 
 // BuiltinMap is a map of builtin descriptors by name
@@ -23,11 +24,17 @@ func AddBuiltin(desc BuiltinDesc) {
 	BuiltinMap[desc.Name] = desc
 }
 
-// LoadBuiltins loads defined builtins in the VM
+// LoadBuiltins loads defined builtins in the VM. In safe mode, unsafe
+// builtins fail when called.
 // - vm: the VM to load builtins into
-func LoadBuiltins(vm *env.Env) {
+// - safe: tells if we are in safe mode
+func LoadBuiltins(vm *env.Env, safe bool) {
 	for name, descriptor := range BuiltinMap {
-		if err := vm.Define(name, descriptor.Func); err != nil {
+		function := descriptor.Func
+		if safe && descriptor.Unsafe {
+			function = unsafeFunction(name)
+		}
+		if err := vm.Define(name, function); err != nil {
 			panic("loading builtin " + name)
 		}
 	}
@@ -39,14 +39,20 @@ var (
 
 // Context is the context of the build
 // - VM: Anko VM that holds build properties
+// - Evaluator: evaluates expressions in the language of the build
 // - Build: the current build
 // - Index: tracks steps index while running build
 // - Stack: tracks targets calls
 type Context struct {
-	VM      *env.Env
-	Build   *Build
-	Stack   *Stack
-	History *History
+	VM         *env.Env
+	Evaluator  Evaluator
+	Build      *Build
+	Stack      *Stack
+	History    *History
+	lazy       *lazyLookup
+	finalizers *finalizers
+	// packages that may be imported, nil if not in safe mode
+	safePackages []string
 }
 
 // NewContext make a new build context
@@ -55,12 +61,19 @@ func NewContext(build *Build) *Context {
 	e := env.NewEnv()
 	core.Import(e)
 	core.ImportToX(e)
-	LoadBuiltins(e)
+	safe := build.IsSafe()
+	LoadBuiltins(e, safe)
+	applySafeMode(e, safe)
 	context := &Context{
-		VM:      e,
-		Build:   build,
-		Stack:   NewStack(),
-		History: NewHistory(),
+		VM:         e,
+		Evaluator:  Languages[build.GetLanguage()],
+		Build:      build,
+		Stack:      NewStack(),
+		History:    NewHistory(),
+		finalizers: newFinalizers(),
+	}
+	if safe {
+		context.safePackages = build.AllowedPackages()
 	}
 	return context
 }
@@ -69,10 +82,17 @@ func NewContext(build *Build) *Context {
 // Return: a pointer to the context copy
 func (context *Context) Copy() *Context {
 	another := &Context{
-		VM:      context.VM.DeepCopy(),
-		Build:   context.Build,
-		Stack:   context.Stack.Copy(),
-		History: context.History.Copy(),
+		VM:           context.VM.DeepCopy(),
+		Evaluator:    context.Evaluator,
+		Build:        context.Build,
+		Stack:        context.Stack.Copy(),
+		History:      context.History.Copy(),
+		finalizers:   context.finalizers,
+		safePackages: context.safePackages,
+	}
+	if context.lazy != nil {
+		another.lazy = context.lazy.copy(another)
+		another.VM.SetExternalLookup(another.lazy)
 	}
 	return another
 }
@@ -83,9 +103,15 @@ func (context *Context) Init() error {
 	if err := context.InitScripts(); err != nil {
 		return fmt.Errorf("loading scripts: %v", err)
 	}
+	if err := context.InitSecrets(); err != nil {
+		return fmt.Errorf("loading secrets: %v", err)
+	}
 	if err := context.InitProperties(); err != nil {
 		return fmt.Errorf("evaluating properties: %v", err)
 	}
+	if err := context.ValidateProperties(); err != nil {
+		return fmt.Errorf("validating properties: %v", err)
+	}
 	if err := context.InitEnvironment(); err != nil {
 		return fmt.Errorf("evaluating environment: %v", err)
 	}
@@ -96,16 +122,22 @@ func (context *Context) Init() error {
 // Return: an error if something went wrong
 func (context *Context) InitScripts() error {
 	scripts := context.Build.GetScripts()
+	if len(scripts) > 0 && context.Build.IsSafe() {
+		return fmt.Errorf("scripts are not allowed in safe mode")
+	}
 	for _, script := range scripts {
 		path, err := context.Build.ScriptPath(script)
 		if err != nil {
 			return fmt.Errorf("getting script path '%s': %v", script, err)
 		}
+		if err := context.Build.VerifyIntegrity(script, path); err != nil {
+			return fmt.Errorf("verifying script '%s': %v", script, err)
+		}
 		source, err := os.ReadFile(path)
 		if err != nil {
 			return fmt.Errorf("reading script '%s': %v", script, err)
 		}
-		_, err = vm.Execute(context.VM, nil, string(source))
+		_, err = context.execute(string(source))
 		if err != nil {
 			return fmt.Errorf("evaluating script '%s': %v", script, FormatScriptError(err))
 		}
@@ -122,7 +154,22 @@ func (context *Context) InitProperties() error {
 	context.SetProperty(propertyBase, context.Build.Dir)
 	context.SetProperty(propertyHere, context.Build.Here)
 	context.SetProperty(propertyRepo, context.Build.Repository)
-	todo := context.Build.Properties.Fields()
+	lazy := make(map[string]interface{})
+	for _, name := range context.Build.LazyProperties() {
+		if value, ok := context.Build.Properties[name]; ok {
+			lazy[name] = value
+		}
+	}
+	if len(lazy) > 0 {
+		context.lazy = newLazyLookup(context, lazy)
+		context.VM.SetExternalLookup(context.lazy)
+	}
+	var todo []string
+	for _, name := range context.Build.Properties.Fields() {
+		if _, ok := lazy[name]; !ok {
+			todo = append(todo, name)
+		}
+	}
 	var crash error
 	for len(todo) > 0 {
 		var done []string
@@ -199,20 +246,73 @@ func (context *Context) DelProperty(name string) {
 	context.VM.Delete(name)
 }
 
+// Properties returns values of all properties in context, without builtins
+// and functions. Lazy properties are evaluated.
+// Return:
+// - properties values by name
+// - an error if a lazy property could not be evaluated
+func (context *Context) Properties() (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	for _, name := range context.VM.GetValueSymbols() {
+		if _, ok := BuiltinMap[name]; ok {
+			continue
+		}
+		value, err := context.VM.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil && reflect.TypeOf(value).Kind() == reflect.Func {
+			continue
+		}
+		properties[name] = value
+	}
+	if context.lazy != nil {
+		for name := range context.lazy.state.properties {
+			if _, ok := properties[name]; ok {
+				continue
+			}
+			value, err := context.EvaluateExpression(name)
+			if err != nil {
+				return nil, fmt.Errorf("evaluating lazy property '%s': %v", name, err)
+			}
+			properties[name] = value
+		}
+	}
+	return properties, nil
+}
+
 // EvaluateExpression evaluate given expression in the context
 // - expression: the expression to evaluate
 // Return:
 // - the return value of the expression
 // - an error if something went wrong
 func (context *Context) EvaluateExpression(expression string) (interface{}, error) {
-	value, err := vm.Execute(context.VM, nil, expression)
+	return context.evaluate(context.Evaluator, expression)
+}
+
+// ExecuteScript runs given Anko script in the context, whatever the language
+// of expressions
+// - script: the script to run
+// Return:
+// - the return value of the script
+// - an error if something went wrong
+func (context *Context) ExecuteScript(script string) (interface{}, error) {
+	return context.evaluate(AnkoEvaluator{}, script)
+}
+
+func (context *Context) evaluate(evaluator Evaluator, source string) (interface{}, error) {
+	if context.lazy != nil {
+		context.lazy.Error()
+	}
+	value, err := evaluator.Evaluate(context, source)
 	if err != nil {
-		return nil, FormatScriptError(err)
+		if context.lazy != nil {
+			if lazyErr := context.lazy.Error(); lazyErr != nil {
+				return nil, lazyErr
+			}
+		}
+		return nil, err
 	}
-	// TODO: check that this is not necessary anymore
-	// if !value.IsValid() {
-	// 	return nil, nil
-	// }
 	return value, nil
 }
 
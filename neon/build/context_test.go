@@ -162,6 +162,22 @@ func TestGetSetProperty(t *testing.T) {
 	}
 }
 
+func TestProperties(t *testing.T) {
+	context := NewContext(nil)
+	context.SetProperty("foo", "bar")
+	context.SetProperty("function", func() string { return "spam" })
+	properties, err := context.Properties()
+	if err != nil {
+		t.Fatalf("getting properties: %v", err)
+	}
+	if properties["foo"] != "bar" {
+		t.Errorf("bad property value: %v", properties["foo"])
+	}
+	if _, ok := properties["function"]; ok {
+		t.Errorf("functions should not be properties")
+	}
+}
+
 func TestEvaluateExpression(t *testing.T) {
 	context := NewContext(nil)
 	_, err := context.EvaluateExpression(`foo = "BAR"`)
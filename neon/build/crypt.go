@@ -0,0 +1,130 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+const (
+	// KeyEnv is the environment variable holding the encryption key
+	KeyEnv = "NEON_KEY"
+	// KeyFileEnv is the environment variable holding the path of the key file
+	KeyFileEnv = "NEON_KEY_FILE"
+	// DefaultKeyFile is the default key file
+	DefaultKeyFile = "~/.neon/neon.key"
+)
+
+// KeyFile is the key file used if environment doesn't define encryption key
+var KeyFile = DefaultKeyFile
+
+// EncryptionKey returns the key to encrypt and decrypt configuration files.
+// This key is the value of NEON_KEY environment variable if set, or the
+// content of the file set with NEON_KEY_FILE or of the key file otherwise.
+// Return:
+// - the encryption key
+// - an error if no key was found
+func EncryptionKey() ([]byte, error) {
+	if key := os.Getenv(KeyEnv); key != "" {
+		return []byte(key), nil
+	}
+	file := KeyFile
+	if env := os.Getenv(KeyFileEnv); env != "" {
+		file = env
+	}
+	file = util.ExpandUserHome(file)
+	if !util.FileExists(file) {
+		return nil, fmt.Errorf("no encryption key: set %s environment variable or write key in '%s'", KeyEnv, file)
+	}
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %v", err)
+	}
+	key := strings.TrimSpace(string(source))
+	if key == "" {
+		return nil, fmt.Errorf("key file '%s' is empty", file)
+	}
+	return []byte(key), nil
+}
+
+// ReadConfigurationFile reads a configuration file, decrypting it if
+// necessary:
+// - file: the configuration file to read
+// Return:
+// - the content of the file
+// - an error if something went wrong
+func ReadConfigurationFile(file string) ([]byte, error) {
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !util.IsEncrypted(source) {
+		return source, nil
+	}
+	key, err := EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := util.Decrypt(source, key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting '%s': %v", file, err)
+	}
+	return plain, nil
+}
+
+// EncryptFile encrypts given file in place:
+// - file: the file to encrypt
+// Return: an error if something went wrong
+func EncryptFile(file string) error {
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if util.IsEncrypted(source) {
+		return fmt.Errorf("file '%s' is already encrypted", file)
+	}
+	key, err := EncryptionKey()
+	if err != nil {
+		return err
+	}
+	encrypted, err := util.Encrypt(source, key)
+	if err != nil {
+		return fmt.Errorf("encrypting '%s': %v", file, err)
+	}
+	return writeInPlace(file, encrypted)
+}
+
+// DecryptFile decrypts given file in place:
+// - file: the file to decrypt
+// Return: an error if something went wrong
+func DecryptFile(file string) error {
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if !util.IsEncrypted(source) {
+		return fmt.Errorf("file '%s' is not encrypted", file)
+	}
+	key, err := EncryptionKey()
+	if err != nil {
+		return err
+	}
+	plain, err := util.Decrypt(source, key)
+	if err != nil {
+		return fmt.Errorf("decrypting '%s': %v", file, err)
+	}
+	return writeInPlace(file, plain)
+}
+
+func writeInPlace(file string, data []byte) error {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("stating file '%s': %v", file, err)
+	}
+	if err := os.WriteFile(file, data, stat.Mode()); err != nil {
+		return fmt.Errorf("writing file '%s': %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"os"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestEncryptedConfiguration(t *testing.T) {
+	dir := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	t.Setenv(KeyEnv, "key")
+	config, err := WriteFile(dir, "config.yml", "PASSWORD: 'secret'\n")
+	if err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := EncryptFile(config); err != nil {
+		t.Fatalf("encrypting file: %v", err)
+	}
+	source, err := util.ReadFile(config)
+	Assert(err, nil, t)
+	Assert(util.IsEncrypted(source), true, t)
+	if err := EncryptFile(config); err == nil {
+		t.Errorf("encrypting an encrypted file should fail")
+	}
+	// encrypted configuration is decrypted while parsing build
+	object := util.Object{"configuration": "config.yml"}
+	build := &Build{Dir: dir, Properties: make(util.Object)}
+	if err := ParseConfiguration(object, build); err != nil {
+		t.Fatalf("parsing configuration: %v", err)
+	}
+	Assert(build.Properties["PASSWORD"], "secret", t)
+	// parsing fails with a bad key
+	t.Setenv(KeyEnv, "bad")
+	if err := ParseConfiguration(object, build); err == nil {
+		t.Errorf("parsing configuration with bad key should fail")
+	}
+	t.Setenv(KeyEnv, "key")
+	if err := DecryptFile(config); err != nil {
+		t.Fatalf("decrypting file: %v", err)
+	}
+	source, err = util.ReadFile(config)
+	Assert(err, nil, t)
+	Assert(string(source), "PASSWORD: 'secret'\n", t)
+}
+
+func TestEncryptionKeyFile(t *testing.T) {
+	dir := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	t.Setenv(KeyEnv, "")
+	file, err := WriteFile(dir, "neon.key", "key\n")
+	if err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	t.Setenv(KeyFileEnv, file)
+	key, err := EncryptionKey()
+	Assert(err, nil, t)
+	Assert(string(key), "key", t)
+	t.Setenv(KeyFileEnv, dir+"/missing.key")
+	if _, err := EncryptionKey(); err == nil {
+		t.Errorf("missing key file should fail")
+	}
+}
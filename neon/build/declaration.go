@@ -0,0 +1,243 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+	"gopkg.in/yaml.v2"
+)
+
+// DeclarationTypes is the list of possible types for property declarations
+var DeclarationTypes = []string{"string", "integer", "float", "boolean", "list", "map"}
+
+// Declaration is a structure for a property declaration:
+// - Type: type of the property (one of DeclarationTypes, any type if empty)
+// - Doc: documentation of the property
+// - Enum: allowed values for the property
+// - Pattern: regular expression the property must match
+// - Required: tells if property must be defined
+// - Lazy: tells if property is evaluated on first access
+type Declaration struct {
+	Type     string
+	Doc      string
+	Enum     []string
+	Pattern  string
+	Required bool
+	Lazy     bool
+}
+
+// ParseDeclarations parses declarations field of the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseDeclarations(object util.Object, build *Build) error {
+	build.Declarations = make(map[string]Declaration)
+	if !object.HasField("declarations") {
+		return nil
+	}
+	declarations, err := object.GetObject("declarations")
+	if err != nil {
+		return fmt.Errorf("parsing declarations: %v", err)
+	}
+	for name := range declarations {
+		body, err := declarations.GetObject(name)
+		if err != nil {
+			return fmt.Errorf("parsing declaration of '%s': %v", name, err)
+		}
+		declaration, err := parseDeclaration(body)
+		if err != nil {
+			return fmt.Errorf("parsing declaration of '%s': %v", name, err)
+		}
+		build.Declarations[name] = declaration
+	}
+	return nil
+}
+
+func parseDeclaration(object util.Object) (Declaration, error) {
+	var declaration Declaration
+	if err := object.CheckFields([]string{"type", "doc", "enum", "pattern", "required", "lazy"}); err != nil {
+		return declaration, err
+	}
+	var err error
+	if object.HasField("type") {
+		if declaration.Type, err = object.GetString("type"); err != nil {
+			return declaration, err
+		}
+		if !util.ListContains(DeclarationTypes, declaration.Type) {
+			return declaration, fmt.Errorf("unknown type '%s' (must be one of %s)",
+				declaration.Type, strings.Join(DeclarationTypes, ", "))
+		}
+	}
+	if object.HasField("doc") {
+		if declaration.Doc, err = object.GetString("doc"); err != nil {
+			return declaration, err
+		}
+	}
+	if object.HasField("enum") {
+		list, err := object.GetList("enum")
+		if err != nil {
+			return declaration, err
+		}
+		for _, value := range list {
+			str, err := PropertyToString(value, false)
+			if err != nil {
+				return declaration, fmt.Errorf("bad enum value: %v", err)
+			}
+			declaration.Enum = append(declaration.Enum, str)
+		}
+	}
+	if object.HasField("pattern") {
+		if declaration.Pattern, err = object.GetString("pattern"); err != nil {
+			return declaration, err
+		}
+		if _, err := regexp.Compile(declaration.Pattern); err != nil {
+			return declaration, fmt.Errorf("bad pattern '%s': %v", declaration.Pattern, err)
+		}
+	}
+	if object.HasField("required") {
+		if declaration.Required, err = object.GetBoolean("required"); err != nil {
+			return declaration, err
+		}
+	}
+	if object.HasField("lazy") {
+		if declaration.Lazy, err = object.GetBoolean("lazy"); err != nil {
+			return declaration, err
+		}
+	}
+	return declaration, nil
+}
+
+// GetDeclarations returns property declarations of the build, including those
+// inherited from parents
+// Return: declarations by property name
+func (build *Build) GetDeclarations() map[string]Declaration {
+	declarations := make(map[string]Declaration)
+	for _, parent := range build.Parents {
+		for name, declaration := range parent.GetDeclarations() {
+			declarations[name] = declaration
+		}
+	}
+	for name, declaration := range build.Declarations {
+		declarations[name] = declaration
+	}
+	return declarations
+}
+
+// Validate checks that value of a property matches its declaration:
+// - name: the name of the property
+// - value: the value of the property
+// - defined: tells if property is defined
+// Return: an error if value doesn't match declaration
+func (declaration Declaration) Validate(name string, value interface{}, defined bool) error {
+	if !defined || value == nil {
+		if declaration.Required {
+			return fmt.Errorf("property '%s' is required", name)
+		}
+		return nil
+	}
+	if declaration.Type != "" && !matchesType(value, declaration.Type) {
+		return fmt.Errorf("property '%s' must be of type %s", name, declaration.Type)
+	}
+	if len(declaration.Enum) == 0 && declaration.Pattern == "" {
+		return nil
+	}
+	str, err := PropertyToString(value, false)
+	if err != nil {
+		return fmt.Errorf("formatting property '%s': %v", name, err)
+	}
+	if len(declaration.Enum) > 0 && !util.ListContains(declaration.Enum, str) {
+		return fmt.Errorf("property '%s' must be one of %s but is '%s'", name,
+			strings.Join(declaration.Enum, ", "), str)
+	}
+	if declaration.Pattern != "" && !regexp.MustCompile(declaration.Pattern).MatchString(str) {
+		return fmt.Errorf("property '%s' must match pattern '%s' but is '%s'", name,
+			declaration.Pattern, str)
+	}
+	return nil
+}
+
+// Convert converts a string value, such as set in environment, to declared
+// type. Value is returned unchanged if it can't be converted, so that
+// validation reports the error.
+// - value: the value to convert
+// Return: the converted value
+func (declaration Declaration) Convert(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || declaration.Type == "" || declaration.Type == "string" {
+		return value
+	}
+	var converted interface{}
+	if err := yaml.Unmarshal([]byte(text), &converted); err != nil ||
+		converted == nil || !matchesType(converted, declaration.Type) {
+		return value
+	}
+	return converted
+}
+
+func matchesType(value interface{}, typ string) bool {
+	kind := reflect.TypeOf(value).Kind()
+	switch typ {
+	case "string":
+		return kind == reflect.String
+	case "integer":
+		return kind >= reflect.Int && kind <= reflect.Uint64
+	case "float":
+		return kind == reflect.Float32 || kind == reflect.Float64 ||
+			(kind >= reflect.Int && kind <= reflect.Uint64)
+	case "boolean":
+		return kind == reflect.Bool
+	case "list":
+		return kind == reflect.Slice || kind == reflect.Array
+	case "map":
+		return kind == reflect.Map
+	}
+	return true
+}
+
+// ValidateProperties checks that properties match their declarations
+// Return: an error if a property doesn't match its declaration
+func (context *Context) ValidateProperties() error {
+	declarations := context.Build.GetDeclarations()
+	var names []string
+	for name, declaration := range declarations {
+		// defined lazy properties are validated on first access
+		if _, defined := context.Build.Properties[name]; defined && declaration.Lazy {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := context.GetProperty(name)
+		if err := declarations[name].Validate(name, value, err == nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attributes returns a description of declared type and constraints
+// Return: the list of attributes, such as ["string", "required"]
+func (declaration Declaration) Attributes() []string {
+	var attributes []string
+	if declaration.Type != "" {
+		attributes = append(attributes, declaration.Type)
+	}
+	if declaration.Required {
+		attributes = append(attributes, "required")
+	}
+	if declaration.Lazy {
+		attributes = append(attributes, "lazy")
+	}
+	if len(declaration.Enum) > 0 {
+		attributes = append(attributes, strings.Join(declaration.Enum, "|"))
+	}
+	if declaration.Pattern != "" {
+		attributes = append(attributes, "/"+declaration.Pattern+"/")
+	}
+	return attributes
+}
@@ -0,0 +1,121 @@
+package build
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestParseDeclarations(t *testing.T) {
+	object := util.Object{
+		"declarations": map[interface{}]interface{}{
+			"ENV": map[interface{}]interface{}{
+				"type":     "string",
+				"doc":      "Target environment",
+				"enum":     []interface{}{"dev", "prod"},
+				"required": true,
+			},
+			"PORT": map[interface{}]interface{}{
+				"type": "integer",
+				"enum": []interface{}{80, 443},
+			},
+		},
+	}
+	build := &Build{}
+	if err := ParseDeclarations(object, build); err != nil {
+		t.Fatalf("parsing declarations: %v", err)
+	}
+	Assert(build.Declarations["ENV"], Declaration{Type: "string", Doc: "Target environment",
+		Enum: []string{"dev", "prod"}, Required: true}, t)
+	Assert(build.Declarations["PORT"].Enum, []string{"80", "443"}, t)
+	object = util.Object{
+		"declarations": map[interface{}]interface{}{
+			"ENV": map[interface{}]interface{}{"type": "strnig"},
+		},
+	}
+	if err := ParseDeclarations(object, build); err == nil {
+		t.Errorf("unknown type should fail")
+	}
+}
+
+func TestValidateDeclaration(t *testing.T) {
+	declaration := Declaration{Type: "string", Enum: []string{"dev", "prod"}, Required: true}
+	Assert(declaration.Validate("ENV", "dev", true), nil, t)
+	for _, test := range []struct {
+		value   interface{}
+		defined bool
+		message string
+	}{
+		{nil, false, "property 'ENV' is required"},
+		{nil, true, "property 'ENV' is required"},
+		{42, true, "property 'ENV' must be of type string"},
+		{"prd", true, "property 'ENV' must be one of dev, prod but is 'prd'"},
+	} {
+		err := declaration.Validate("ENV", test.value, test.defined)
+		if err == nil || err.Error() != test.message {
+			t.Errorf("bad validation error for %v: %v", test.value, err)
+		}
+	}
+	declaration = Declaration{Type: "float", Pattern: `^\d+\.\d+$`}
+	Assert(declaration.Validate("VERSION", 1.5, true), nil, t)
+	Assert(declaration.Validate("VERSION", nil, false), nil, t)
+	if err := declaration.Validate("VERSION", 2, true); err == nil {
+		t.Errorf("value should not match pattern")
+	}
+}
+
+func TestValidateProperties(t *testing.T) {
+	build := &Build{
+		Properties: util.Object{"ENV": "prod"},
+		Declarations: map[string]Declaration{
+			"ENV":  {Type: "string", Enum: []string{"dev", "prod"}},
+			"PORT": {Type: "integer", Doc: "Server port", Required: true},
+		},
+	}
+	context := NewContext(build)
+	err := context.Init()
+	if err == nil || !strings.Contains(err.Error(), "property 'PORT' is required") {
+		t.Errorf("missing required property should fail: %v", err)
+	}
+	if err := build.SetCommandLineProperties("{PORT: 8080}", ""); err != nil {
+		t.Fatalf("setting properties: %v", err)
+	}
+	context = NewContext(build)
+	Assert(context.Init(), nil, t)
+	expected := `declarations:
+  ENV:  [string, dev|prod]
+  PORT: Server port [integer, required]
+`
+	Assert(build.infoDeclarations(), expected, t)
+}
+
+func TestConvertDeclaration(t *testing.T) {
+	_ = os.Setenv("NEON_PROP_PORT", "8080")
+	_ = os.Setenv("NEON_PROP_DEBUG", "maybe")
+	defer func() {
+		_ = os.Unsetenv("NEON_PROP_PORT")
+		_ = os.Unsetenv("NEON_PROP_DEBUG")
+	}()
+	build := &Build{
+		Properties: map[string]interface{}{},
+		Declarations: map[string]Declaration{
+			"PORT":    {Type: "integer"},
+			"DEBUG":   {Type: "boolean"},
+			"RATIO":   {Type: "float"},
+			"VERSION": {Type: "string"},
+		},
+	}
+	if err := build.SetCommandLineProperties("{RATIO: '0.5', VERSION: 1.0}", ""); err != nil {
+		t.Fatalf("setting properties: %v", err)
+	}
+	Assert(build.Properties["PORT"], 8080, t)
+	Assert(build.Properties["RATIO"], 0.5, t)
+	// values that can't be converted are left unchanged for validation
+	Assert(build.Properties["DEBUG"], "maybe", t)
+	Assert(build.Properties["VERSION"], 1.0, t)
+	if err := build.Declarations["DEBUG"].Validate("DEBUG", build.Properties["DEBUG"], true); err == nil {
+		t.Errorf("value that can't be converted should fail validation")
+	}
+}
@@ -0,0 +1,888 @@
+package build
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file implements typed, a small expression language with strict types
+// (see its grammar in section Expression languages of user manual):
+// - literals: integers, floats, strings, true, false, nil, lists and maps
+// - operators: + - * / % == != < <= > >= && || ! in and c ? a : b
+// - property access with their name, indexing with [] and map fields with .
+// - calls to builtin functions
+// Integers are always of type int and are promoted to float when mixed with
+// floats. There is no other implicit conversion: adding a string and an int
+// or comparing them is an error.
+
+// exprToken is a token of an expression
+type exprToken struct {
+	kind  string
+	text  string
+	value interface{}
+	pos   int
+}
+
+// token kinds
+const (
+	exprEOF    = "end of expression"
+	exprNumber = "number"
+	exprString = "string"
+	exprIdent  = "identifier"
+	exprOp     = "operator"
+)
+
+// exprOperators is the list of operators, longest first
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%",
+	"<", ">", "!", "(", ")", "[", "]", "{", "}", ",", ":", "?", "."}
+
+// lexExpr splits an expression into tokens
+func lexExpr(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			float := false
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '_' ||
+				runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				if runes[i] == '.' {
+					// stop on field access such as 1.foo
+					if i+1 >= len(runes) || !unicode.IsDigit(runes[i+1]) {
+						break
+					}
+					float = true
+				}
+				if runes[i] == 'e' || runes[i] == 'E' {
+					float = true
+				}
+				i++
+			}
+			text := string(runes[start:i])
+			var value interface{}
+			var err error
+			if float {
+				value, err = strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+			} else {
+				var integer int64
+				integer, err = strconv.ParseInt(strings.ReplaceAll(text, "_", ""), 10, 0)
+				value = int(integer)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("bad number '%s' at position %d", text, start+1)
+			}
+			tokens = append(tokens, exprToken{kind: exprNumber, text: text, value: value, pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var builder strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						builder.WriteRune('\n')
+					case 't':
+						builder.WriteRune('\t')
+					case 'r':
+						builder.WriteRune('\r')
+					default:
+						builder.WriteRune(runes[i])
+					}
+				} else {
+					builder.WriteRune(runes[i])
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: exprString, text: string(runes[start:i]),
+				value: builder.String(), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprIdent, text: string(runes[start:i]), pos: start})
+		default:
+			found := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, exprToken{kind: exprOp, text: op, pos: i})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i+1)
+			}
+		}
+	}
+	tokens = append(tokens, exprToken{kind: exprEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// exprNode is a node of the syntax tree of an expression
+type exprNode interface {
+	eval(scope exprScope) (interface{}, error)
+}
+
+// exprScope resolves properties and functions while evaluating an expression
+type exprScope interface {
+	property(name string) (interface{}, error)
+	function(name string) (reflect.Value, error)
+}
+
+type (
+	exprLiteral struct{ value interface{} }
+	exprName    struct{ name string }
+	exprList    struct{ items []exprNode }
+	exprMap     struct{ keys, values []exprNode }
+	exprUnary   struct {
+		op      string
+		operand exprNode
+	}
+	exprBinary struct {
+		op          string
+		left, right exprNode
+	}
+	exprTernary struct{ cond, then, otherwise exprNode }
+	exprIndex   struct{ object, index exprNode }
+	exprCall    struct {
+		name string
+		args []exprNode
+	}
+)
+
+// exprParser is a recursive descent parser for expressions
+type exprParser struct {
+	tokens []exprToken
+	index  int
+}
+
+// parseExpr parses an expression of the expr language:
+// - source: the source of the expression
+// Return:
+// - the syntax tree of the expression
+// - an error if expression is invalid
+func parseExpr(source string) (exprNode, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &exprParser{tokens: tokens}
+	node, err := parser.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != exprEOF {
+		return nil, parser.unexpected(token)
+	}
+	return node, nil
+}
+
+func (parser *exprParser) peek() exprToken {
+	return parser.tokens[parser.index]
+}
+
+func (parser *exprParser) next() exprToken {
+	token := parser.tokens[parser.index]
+	if token.kind != exprEOF {
+		parser.index++
+	}
+	return token
+}
+
+func (parser *exprParser) accept(ops ...string) (string, bool) {
+	token := parser.peek()
+	if token.kind != exprOp && token.kind != exprIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if token.text == op {
+			parser.index++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (parser *exprParser) expect(op string) error {
+	if _, ok := parser.accept(op); !ok {
+		token := parser.peek()
+		if token.kind == exprEOF {
+			return fmt.Errorf("expected '%s' at end of expression", op)
+		}
+		return fmt.Errorf("expected '%s' at position %d but found '%s'", op, token.pos+1, token.text)
+	}
+	return nil
+}
+
+func (parser *exprParser) unexpected(token exprToken) error {
+	if token.kind == exprEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", token.text, token.pos+1)
+}
+
+func (parser *exprParser) ternary() (exprNode, error) {
+	cond, err := parser.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parser.accept("?"); !ok {
+		return cond, nil
+	}
+	then, err := parser.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := parser.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return exprTernary{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// exprLevels lists binary operators by increasing precedence
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (parser *exprParser) binary(level int) (exprNode, error) {
+	if level >= len(exprLevels) {
+		return parser.unary()
+	}
+	left, err := parser.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := parser.accept(exprLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := parser.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (parser *exprParser) unary() (exprNode, error) {
+	if op, ok := parser.accept("-", "!"); ok {
+		operand, err := parser.unary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{op: op, operand: operand}, nil
+	}
+	return parser.postfix()
+}
+
+func (parser *exprParser) postfix() (exprNode, error) {
+	node, err := parser.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := parser.accept("("); ok {
+			name, ok := node.(exprName)
+			if !ok {
+				return nil, fmt.Errorf("only builtin functions may be called")
+			}
+			args, err := parser.items(")")
+			if err != nil {
+				return nil, err
+			}
+			node = exprCall{name: name.name, args: args}
+		} else if _, ok := parser.accept("["); ok {
+			index, err := parser.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := parser.expect("]"); err != nil {
+				return nil, err
+			}
+			node = exprIndex{object: node, index: index}
+		} else if _, ok := parser.accept("."); ok {
+			token := parser.next()
+			if token.kind != exprIdent {
+				return nil, parser.unexpected(token)
+			}
+			node = exprIndex{object: node, index: exprLiteral{value: token.text}}
+		} else {
+			return node, nil
+		}
+	}
+}
+
+func (parser *exprParser) items(closing string) ([]exprNode, error) {
+	var items []exprNode
+	if _, ok := parser.accept(closing); ok {
+		return items, nil
+	}
+	for {
+		item, err := parser.ternary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := parser.accept(closing); ok {
+			return items, nil
+		}
+		if err := parser.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *exprParser) primary() (exprNode, error) {
+	token := parser.next()
+	switch token.kind {
+	case exprNumber, exprString:
+		return exprLiteral{value: token.value}, nil
+	case exprIdent:
+		switch token.text {
+		case "true":
+			return exprLiteral{value: true}, nil
+		case "false":
+			return exprLiteral{value: false}, nil
+		case "nil":
+			return exprLiteral{value: nil}, nil
+		case "in":
+			return nil, parser.unexpected(token)
+		}
+		return exprName{name: token.text}, nil
+	case exprOp:
+		switch token.text {
+		case "(":
+			node, err := parser.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := parser.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			items, err := parser.items("]")
+			if err != nil {
+				return nil, err
+			}
+			return exprList{items: items}, nil
+		case "{":
+			node := exprMap{}
+			if _, ok := parser.accept("}"); ok {
+				return node, nil
+			}
+			for {
+				key, err := parser.ternary()
+				if err != nil {
+					return nil, err
+				}
+				if err := parser.expect(":"); err != nil {
+					return nil, err
+				}
+				value, err := parser.ternary()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key)
+				node.values = append(node.values, value)
+				if _, ok := parser.accept("}"); ok {
+					return node, nil
+				}
+				if err := parser.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, parser.unexpected(token)
+}
+
+// normalizeExpr converts integers to int and floats to float64
+func normalizeExpr(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return value
+}
+
+// exprType returns the name of the type of a value in error messages
+func exprType(value interface{}) string {
+	if value == nil {
+		return "nil"
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int:
+		return "int"
+	case reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func (node exprLiteral) eval(scope exprScope) (interface{}, error) {
+	return node.value, nil
+}
+
+func (node exprName) eval(scope exprScope) (interface{}, error) {
+	value, err := scope.property(node.name)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeExpr(value), nil
+}
+
+func (node exprList) eval(scope exprScope) (interface{}, error) {
+	list := make([]interface{}, len(node.items))
+	for i, item := range node.items {
+		value, err := item.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, nil
+}
+
+func (node exprMap) eval(scope exprScope) (interface{}, error) {
+	result := make(map[string]interface{})
+	for i, key := range node.keys {
+		k, err := key.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys must be strings, got %s", exprType(k))
+		}
+		value, err := node.values[i].eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+func (node exprUnary) eval(scope exprScope) (interface{}, error) {
+	value, err := node.operand.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "!":
+		if b, ok := value.(bool); ok {
+			return !b, nil
+		}
+	case "-":
+		switch v := value.(type) {
+		case int:
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s not defined on %s", node.op, exprType(value))
+}
+
+func (node exprTernary) eval(scope exprScope) (interface{}, error) {
+	cond, err := node.cond.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := cond.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition must be a bool, got %s", exprType(cond))
+	}
+	if b {
+		return node.then.eval(scope)
+	}
+	return node.otherwise.eval(scope)
+}
+
+func (node exprBinary) eval(scope exprScope) (interface{}, error) {
+	left, err := node.left.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	// logical operators are short-circuited
+	if node.op == "&&" || node.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires bools, got %s", node.op, exprType(left))
+		}
+		if (node.op == "&&" && !l) || (node.op == "||" && l) {
+			return l, nil
+		}
+		right, err := node.right.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires bools, got %s", node.op, exprType(right))
+		}
+		return r, nil
+	}
+	right, err := node.right.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "==", "!=":
+		equal, err := exprEqual(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return equal == (node.op == "=="), nil
+	case "in":
+		return exprIn(left, right)
+	case "<", "<=", ">", ">=":
+		return exprCompare(node.op, left, right)
+	}
+	return exprArithmetic(node.op, left, right)
+}
+
+func exprNumbers(left, right interface{}) (float64, float64, bool) {
+	var l, r float64
+	switch v := left.(type) {
+	case int:
+		l = float64(v)
+	case float64:
+		l = v
+	default:
+		return 0, 0, false
+	}
+	switch v := right.(type) {
+	case int:
+		r = float64(v)
+	case float64:
+		r = v
+	default:
+		return 0, 0, false
+	}
+	return l, r, true
+}
+
+func exprArithmetic(op string, left, right interface{}) (interface{}, error) {
+	undefined := fmt.Errorf("operator %s not defined on %s and %s", op, exprType(left), exprType(right))
+	if l, ok := left.(int); ok {
+		if r, ok := right.(int); ok {
+			switch op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			case "*":
+				return l * r, nil
+			case "/", "%":
+				if r == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				if op == "/" {
+					return l / r, nil
+				}
+				return l % r, nil
+			}
+		}
+	}
+	if l, r, ok := exprNumbers(left, right); ok {
+		switch op {
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			return l / r, nil
+		case "%":
+			return math.Mod(l, r), nil
+		}
+	}
+	if op != "+" {
+		return nil, undefined
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+		return nil, undefined
+	}
+	if left != nil && right != nil &&
+		reflect.TypeOf(left).Kind() == reflect.Slice && reflect.TypeOf(right).Kind() == reflect.Slice {
+		var list []interface{}
+		for _, side := range []interface{}{left, right} {
+			value := reflect.ValueOf(side)
+			for i := 0; i < value.Len(); i++ {
+				list = append(list, normalizeExpr(value.Index(i).Interface()))
+			}
+		}
+		return list, nil
+	}
+	return nil, undefined
+}
+
+func exprEqual(left, right interface{}) (bool, error) {
+	if left == nil || right == nil {
+		return left == nil && right == nil, nil
+	}
+	if l, r, ok := exprNumbers(left, right); ok {
+		return l == r, nil
+	}
+	if exprType(left) != exprType(right) {
+		return false, fmt.Errorf("cannot compare %s and %s", exprType(left), exprType(right))
+	}
+	if exprType(left) == "list" {
+		l, r := reflect.ValueOf(left), reflect.ValueOf(right)
+		if l.Len() != r.Len() {
+			return false, nil
+		}
+		for i := 0; i < l.Len(); i++ {
+			equal, err := exprEqual(normalizeExpr(l.Index(i).Interface()), normalizeExpr(r.Index(i).Interface()))
+			if err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return reflect.DeepEqual(left, right), nil
+}
+
+func exprCompare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	if l, r, ok := exprNumbers(left, right); ok {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s and %s", exprType(left), exprType(right))
+		}
+		cmp = strings.Compare(l, r)
+	} else {
+		return nil, fmt.Errorf("cannot compare %s and %s", exprType(left), exprType(right))
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+func exprIn(element, container interface{}) (interface{}, error) {
+	if str, ok := container.(string); ok {
+		sub, ok := element.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator in requires a string in a string, got %s", exprType(element))
+		}
+		return strings.Contains(str, sub), nil
+	}
+	if container == nil {
+		return nil, fmt.Errorf("operator in not defined on nil")
+	}
+	value := reflect.ValueOf(container)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if equal, err := exprEqual(element, normalizeExpr(value.Index(i).Interface())); err == nil && equal {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		_, found, err := exprMapGet(value, element)
+		return found, err
+	}
+	return nil, fmt.Errorf("operator in not defined on %s", exprType(container))
+}
+
+func exprMapGet(value reflect.Value, key interface{}) (interface{}, bool, error) {
+	if key == nil {
+		return nil, false, fmt.Errorf("map key can't be nil")
+	}
+	k := reflect.ValueOf(key)
+	if !k.Type().AssignableTo(value.Type().Key()) {
+		if !k.Type().ConvertibleTo(value.Type().Key()) || value.Type().Key().Kind() == reflect.String &&
+			k.Kind() != reflect.String {
+			return nil, false, fmt.Errorf("bad key type %s for map", exprType(key))
+		}
+		k = k.Convert(value.Type().Key())
+	}
+	result := value.MapIndex(k)
+	if !result.IsValid() {
+		return nil, false, nil
+	}
+	return normalizeExpr(result.Interface()), true, nil
+}
+
+func (node exprIndex) eval(scope exprScope) (interface{}, error) {
+	object, err := node.object.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	index, err := node.index.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("can't index nil")
+	}
+	value := reflect.ValueOf(object)
+	switch value.Kind() {
+	case reflect.Map:
+		result, found, err := exprMapGet(value, index)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("key '%v' not found in map", index)
+		}
+		return result, nil
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, ok := index.(int)
+		if !ok {
+			return nil, fmt.Errorf("index must be an int, got %s", exprType(index))
+		}
+		if value.Kind() == reflect.String {
+			runes := []rune(object.(string))
+			if i < 0 || i >= len(runes) {
+				return nil, fmt.Errorf("index %d out of range", i)
+			}
+			return string(runes[i]), nil
+		}
+		if i < 0 || i >= value.Len() {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return normalizeExpr(value.Index(i).Interface()), nil
+	}
+	return nil, fmt.Errorf("can't index %s", exprType(object))
+}
+
+func (node exprCall) eval(scope exprScope) (result interface{}, err error) {
+	function, err := scope.function(node.name)
+	if err != nil {
+		return nil, err
+	}
+	typ := function.Type()
+	count := len(node.args)
+	if (!typ.IsVariadic() && count != typ.NumIn()) || (typ.IsVariadic() && count < typ.NumIn()-1) {
+		return nil, fmt.Errorf("function %s expects %d arguments, got %d", node.name, typ.NumIn(), count)
+	}
+	args := make([]reflect.Value, count)
+	for i, arg := range node.args {
+		value, err := arg.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		var expected reflect.Type
+		if typ.IsVariadic() && i >= typ.NumIn()-1 {
+			expected = typ.In(typ.NumIn() - 1).Elem()
+		} else {
+			expected = typ.In(i)
+		}
+		args[i], err = exprConvert(value, expected)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %v", i+1, node.name, err)
+		}
+	}
+	// builtins may panic, we return an error instead
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("calling %s: %v", node.name, r)
+		}
+	}()
+	results := function.Call(args)
+	if len(results) > 0 {
+		last := results[len(results)-1]
+		if last.Type() == reflect.TypeOf((*error)(nil)).Elem() {
+			if !last.IsNil() {
+				return nil, fmt.Errorf("calling %s: %v", node.name, last.Interface())
+			}
+			results = results[:len(results)-1]
+		}
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return normalizeExpr(results[0].Interface()), nil
+}
+
+// exprConvert converts a value to the type of a function argument
+func exprConvert(value interface{}, typ reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(typ), nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(typ) {
+		return v, nil
+	}
+	numeric := func(kind reflect.Kind) bool {
+		return kind >= reflect.Int && kind <= reflect.Float64
+	}
+	if numeric(v.Kind()) && numeric(typ.Kind()) {
+		return v.Convert(typ), nil
+	}
+	if v.Kind() == reflect.Slice && typ.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(typ, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := exprConvert(v.Index(i).Interface(), typ.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(i).Set(item)
+		}
+		return slice, nil
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, got %s", typ, exprType(value))
+}
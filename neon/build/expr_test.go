@@ -0,0 +1,128 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func exprTestContext(t *testing.T) *Context {
+	builtins := BuiltinMap
+	t.Cleanup(func() {
+		BuiltinMap = builtins
+	})
+	BuiltinMap = make(map[string]BuiltinDesc)
+	AddBuiltin(BuiltinDesc{
+		Name: "join",
+		Func: strings.Join,
+	})
+	AddBuiltin(BuiltinDesc{
+		Name: "repeat",
+		Func: func(text string, count int64) string { return strings.Repeat(text, int(count)) },
+	})
+	build := &Build{
+		Language: "typed",
+		Properties: util.Object{
+			"NAME":    "neon",
+			"COUNT":   3,
+			"RATIO":   0.5,
+			"LIST":    []interface{}{"a", "b"},
+			"MAP":     map[interface{}]interface{}{"key": "value"},
+			"VERSION": "={NAME}-{COUNT}",
+			"DOUBLE":  "=COUNT * 2",
+		},
+	}
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	return context
+}
+
+func TestExprEvaluate(t *testing.T) {
+	context := exprTestContext(t)
+	for expression, expected := range map[string]interface{}{
+		`1 + 2 * 3`:                       7,
+		`(1 + 2) * 3`:                     9,
+		`7 / 2`:                           3,
+		`7 % 2`:                           1,
+		`1 + 0.5`:                         1.5,
+		`-COUNT`:                          -3,
+		`COUNT * RATIO`:                   1.5,
+		`"foo" + 'bar'`:                   "foobar",
+		`"a\"b"`:                          `a"b`,
+		`NAME + "-" + "1.0"`:              "neon-1.0",
+		`DOUBLE`:                          6,
+		`COUNT == 3 && !(NAME != "neon")`: true,
+		`COUNT > 1 || 1 / 0 == 1`:         true,
+		`"b" in LIST`:                     true,
+		`"c" in LIST`:                     false,
+		`"key" in MAP`:                    true,
+		`"eo" in NAME`:                    true,
+		`LIST[1]`:                         "b",
+		`MAP.key`:                         "value",
+		`MAP["key"]`:                      "value",
+		`{"foo": 1}.foo`:                  1,
+		`COUNT > 2 ? "many" : "few"`:      "many",
+		`nil == nil`:                      true,
+		`[1, 2] + [3]`:                    []interface{}{1, 2, 3},
+		`[1, 2] == [1, 2]`:                true,
+		`join(LIST, ", ")`:                "a, b",
+		`repeat("ab", COUNT)`:             "ababab",
+	} {
+		actual, err := context.EvaluateExpression(expression)
+		if err != nil {
+			t.Errorf("evaluating '%s': %v", expression, err)
+			continue
+		}
+		Assert(actual, expected, t)
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	context := exprTestContext(t)
+	for expression, expected := range map[string]string{
+		`1 +`:           "parsing expression: unexpected end of expression",
+		`(1 + 2`:        "parsing expression: expected ')' at end of expression",
+		`1 $ 2`:         "parsing expression: unexpected character '$' at position 3",
+		`"foo`:          "parsing expression: unterminated string at position 1",
+		`NAME + COUNT`:  "operator + not defined on string and int",
+		`NAME == COUNT`: "cannot compare string and int",
+		`COUNT && true`: "operator && requires bools, got int",
+		`COUNT ? 1 : 2`: "condition must be a bool, got int",
+		`1 / 0`:         "division by zero",
+		`MISSING`:       "undefined property 'MISSING'",
+		`LIST[2]`:       "index 2 out of range",
+		`MAP.missing`:   "key 'missing' not found in map",
+		`unknown(1)`:    "unknown builtin 'unknown'",
+		`join`:          "builtin join must be called",
+		`join(LIST)`:    "function join expects 2 arguments, got 1",
+		`repeat(1, 2)`:  "argument 1 of repeat: expected string, got int",
+		`NAME = "foo"`:  "parsing expression: unexpected character '=' at position 6",
+		`LIST(1)`:       "unknown builtin 'LIST'",
+		`LIST[0](1)`:    "parsing expression: only builtin functions may be called",
+	} {
+		_, err := context.EvaluateExpression(expression)
+		if err == nil || err.Error() != expected {
+			t.Errorf("bad error for '%s': %v", expression, err)
+		}
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	build := &Build{}
+	if err := ParseLanguage(util.Object{"language": "typed"}, build); err != nil {
+		t.Fatalf("parsing language: %v", err)
+	}
+	Assert(build.GetLanguage(), "typed", t)
+	err := ParseLanguage(util.Object{"language": "python"}, build)
+	if err == nil || err.Error() != "unknown language 'python' (must be one of anko, typed)" {
+		t.Errorf("bad error for unknown language: %v", err)
+	}
+	Assert((&Build{}).GetLanguage(), "anko", t)
+	build = &Build{Parents: []*Build{{File: "parent.yml", Language: "anko"}}}
+	if err := build.checkLanguage("typed"); err == nil {
+		t.Errorf("parent with another language should fail")
+	}
+}
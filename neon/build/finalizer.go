@@ -0,0 +1,47 @@
+package build
+
+import (
+	"sync"
+)
+
+// finalizers holds functions to call when targets or the build end. Functions
+// registered for the build are stored with nil target.
+type finalizers struct {
+	mutex     sync.Mutex
+	functions map[*Target][]func() error
+}
+
+func newFinalizers() *finalizers {
+	return &finalizers{functions: make(map[*Target][]func() error)}
+}
+
+// AddFinalizer registers a function to call when current target ends, even
+// on failure. Functions are called in reverse order of registration.
+// - build: if true, call function when build ends instead of current target
+// - finalizer: the function to call
+func (context *Context) AddFinalizer(build bool, finalizer func() error) {
+	var target *Target
+	if !build {
+		target = context.Stack.Last()
+	}
+	context.finalizers.mutex.Lock()
+	defer context.finalizers.mutex.Unlock()
+	context.finalizers.functions[target] = append(context.finalizers.functions[target], finalizer)
+}
+
+// RunFinalizers calls functions registered for given target:
+// - target: the target that ends, nil for the build
+// Return: the first error returned by functions
+func (context *Context) RunFinalizers(target *Target) error {
+	context.finalizers.mutex.Lock()
+	functions := context.finalizers.functions[target]
+	delete(context.finalizers.functions, target)
+	context.finalizers.mutex.Unlock()
+	var first error
+	for i := len(functions) - 1; i >= 0; i-- {
+		if err := functions[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
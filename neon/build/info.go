@@ -11,8 +11,10 @@ import (
 	"github.com/c4s4/neon/neon/util"
 )
 
-// Info generates information about build on console.
-// - context: context of the build
+// Info generates information about build on console. If context is nil,
+// information is static: properties and singleton are printed as written in
+// build file, without running scripts or evaluating expressions.
+// - context: context of the build (or nil for static information)
 // Return: build info as a string and an error if something went wrong
 func (build *Build) Info(context *Context) (string, error) {
 	info := ""
@@ -36,6 +38,10 @@ func (build *Build) Info(context *Context) (string, error) {
 	if props != "" {
 		info += props + "\n"
 	}
+	declarations := build.infoDeclarations()
+	if declarations != "" {
+		info += declarations + "\n"
+	}
 	targets := build.infoTargets()
 	if targets != "" {
 		info += targets
@@ -71,6 +77,9 @@ func (build *Build) infoRepository() string {
 func (build *Build) infoSingleton(context *Context) string {
 	info := ""
 	if build.Singleton != "" {
+		if context == nil {
+			return fmt.Sprintf("singleton: %s\n", build.Singleton)
+		}
 		port, err := context.EvaluateExpression(build.Singleton)
 		if err == nil {
 			info += fmt.Sprintf("singleton: %v\n", port)
@@ -125,20 +134,45 @@ func (build *Build) infoProperties(context *Context) (string, error) {
 	if len(names) > 0 {
 		info += "properties:\n"
 		for _, name := range names {
-			value, err := context.GetProperty(name)
-			if err != nil {
-				return "", fmt.Errorf("getting property '%s': %v", name, err)
+			value := build.Properties[name]
+			if context != nil {
+				var err error
+				value, err = context.GetProperty(name)
+				if err != nil {
+					return "", fmt.Errorf("getting property '%s': %v", name, err)
+				}
 			}
 			valueStr, err := PropertyToString(value, true)
 			if err != nil {
 				return "", fmt.Errorf("formatting property '%s': %v", name, err)
 			}
-			info += FormatTarget(name, valueStr, []string{}, length) + "\n"
+			info += FormatTarget(name, Mask(valueStr), []string{}, length) + "\n"
 		}
 	}
 	return info, nil
 }
 
+func (build *Build) infoDeclarations() string {
+	info := ""
+	declarations := build.GetDeclarations()
+	var names []string
+	for name := range declarations {
+		if build.Expose == nil || util.ListContains(build.Expose, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	length := util.MaxLineLength(names)
+	if len(names) > 0 {
+		info += "declarations:\n"
+		for _, name := range names {
+			declaration := declarations[name]
+			info += FormatTarget(name, declaration.Doc, declaration.Attributes(), length) + "\n"
+		}
+	}
+	return info
+}
+
 func (build *Build) infoEnvironment() string {
 	info := ""
 	var names []string
@@ -283,7 +317,7 @@ func InfoTemplates(repository string) string {
 // Return: parents info as a string
 func InfoParents(repository string) string {
 	info := ""
-	files, err := util.FindFiles(repository, []string{"*/*/*.yml"}, nil, false)
+	files, err := util.FindFiles(repository, []string{"*/*/*.yml"}, ExcludeCache, false)
 	if err != nil {
 		panic(err)
 	}
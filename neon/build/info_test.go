@@ -161,6 +161,30 @@ func TestInfoProperties(t *testing.T) {
 	}
 }
 
+func TestInfoPropertiesStatic(t *testing.T) {
+	build := &Build{
+		Singleton: "=PORT",
+		Properties: map[string]interface{}{
+			"PORT": "=12345",
+			"foo":  "=run('rm', '-rf', '/')",
+			"bar":  []interface{}{"eggs", "={foo}"},
+		},
+	}
+	properties, err := build.infoProperties(nil)
+	if err != nil {
+		t.Errorf("getting properties: %v", err)
+	}
+	expected := `properties:
+  PORT: "=12345"
+  bar:  ["eggs", "={foo}"]
+  foo:  "=run('rm', '-rf', '/')"
+`
+	if properties != expected {
+		t.Errorf("Bad properties info: %s", properties)
+	}
+	Assert(build.infoSingleton(nil), "singleton: =PORT\n", t)
+}
+
 func TestInfoEnvironment(t *testing.T) {
 	build := &Build{
 		Environment: map[string]string{
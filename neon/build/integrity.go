@@ -0,0 +1,176 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// LockFile is the name of the file with checksums of repository files
+	LockFile = "neon.lock"
+	// ChecksumPrefix is the prefix for checksums
+	ChecksumPrefix = "sha256:"
+)
+
+// CheckIntegrity tells if we should verify checksums of repository files
+var CheckIntegrity = true
+
+// Checksum computes checksum of given file:
+// - file: the file to compute checksum for
+// Return:
+// - checksum as a string (such as "sha256:2c26b46b68ffc68ff99b453c1d3041...")
+// - an error if something went wrong
+func Checksum(file string) (string, error) {
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(source)
+	return ChecksumPrefix + hex.EncodeToString(hash[:]), nil
+}
+
+// LoadLockFile loads lock file in given directory, if any:
+// - dir: directory of the lock file
+// Return:
+// - checksums by file name (empty if there is no lock file)
+// - an error if something went wrong
+func LoadLockFile(dir string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	file := filepath.Join(dir, LockFile)
+	if !util.FileExists(file) {
+		return checksums, nil
+	}
+	source, err := util.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(source, &checksums); err != nil {
+		return nil, fmt.Errorf("lock file '%s' must be a map with string keys and values", file)
+	}
+	return checksums, nil
+}
+
+// VerifyChecksum verifies checksum of a file:
+// - name: name of the file (as found in build file)
+// - path: path of the file
+// - checksums: checksums by file name
+// - repository: the NeON repository
+// Return: an error if checksum doesn't match
+func VerifyChecksum(name, path string, checksums map[string]string, repository string) error {
+	if !CheckIntegrity {
+		return nil
+	}
+	expected, ok := checksums[name]
+	if !ok {
+		relative, err := filepath.Rel(repository, path)
+		if err != nil {
+			return nil
+		}
+		expected, ok = checksums[util.PathToUnix(relative)]
+		if !ok {
+			return nil
+		}
+	}
+	if !strings.HasPrefix(expected, ChecksumPrefix) {
+		expected = ChecksumPrefix + expected
+	}
+	actual, err := Checksum(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for '%s': expected '%s' but got '%s'", name, expected, actual)
+	}
+	return nil
+}
+
+// VerifyIntegrity verifies checksum of a file used by the build:
+// - name: name of the file (as found in build file)
+// - path: path of the file
+// Return: an error if checksum doesn't match
+func (build *Build) VerifyIntegrity(name, path string) error {
+	return VerifyChecksum(name, path, build.Integrity, build.Repository)
+}
+
+// LockEntries computes checksums of repository and remote files used by the
+// build, that is parent build files and context scripts, including those of
+// parents.
+// Return:
+// - checksums by path relative to repository (or URL for remote files)
+// - an error if something went wrong
+func (build *Build) LockEntries() (map[string]string, error) {
+	entries := make(map[string]string)
+	files := make(map[string]string)
+	for _, extend := range build.Extends {
+		path, err := build.ParentPath(extend)
+		if err != nil {
+			return nil, err
+		}
+		files[extend] = path
+	}
+	for _, script := range build.Scripts {
+		path, err := build.ScriptPath(script)
+		if err != nil {
+			return nil, err
+		}
+		files[script] = path
+	}
+	for name, file := range files {
+		if !IsRemote(name) {
+			relative, err := filepath.Rel(build.Repository, file)
+			if err != nil || strings.HasPrefix(relative, "..") {
+				continue
+			}
+			name = util.PathToUnix(relative)
+		}
+		checksum, err := Checksum(file)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = checksum
+	}
+	for _, parent := range build.Parents {
+		parentEntries, err := parent.LockEntries()
+		if err != nil {
+			return nil, err
+		}
+		for name, checksum := range parentEntries {
+			entries[name] = checksum
+		}
+	}
+	return entries, nil
+}
+
+// WriteLockFile writes lock file with checksums of repository files used by
+// the build in build directory.
+// Return:
+// - path of the lock file
+// - an error if something went wrong
+func (build *Build) WriteLockFile() (string, error) {
+	entries, err := build.LockEntries()
+	if err != nil {
+		return "", fmt.Errorf("computing checksums: %v", err)
+	}
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	content := "# NeON lock file (http://github.com/c4s4/neon)\n\n"
+	for _, name := range names {
+		content += fmt.Sprintf("%s: '%s'\n", name, entries[name])
+	}
+	path := filepath.Join(build.Dir, LockFile)
+	if err := os.WriteFile(path, []byte(content), util.FileMode); err != nil {
+		return "", fmt.Errorf("writing lock file: %v", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,117 @@
+package build
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const checksumFoo = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestChecksum(t *testing.T) {
+	file, err := WriteFile("/tmp", "checksum.txt", "foo")
+	if err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(file)
+	}()
+	checksum, err := Checksum(file)
+	Assert(err, nil, t)
+	Assert(checksum, checksumFoo, t)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	repo := "/tmp/neon"
+	file, err := WriteFile(repo+"/foo/bar", "parent.yml", "foo")
+	if err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	// no checksum
+	if err := VerifyChecksum("parent", file, map[string]string{}, repo); err != nil {
+		t.Errorf("Verification without checksum should succeed: %v", err)
+	}
+	// checksum by name
+	if err := VerifyChecksum("parent", file, map[string]string{"parent": checksumFoo}, repo); err != nil {
+		t.Errorf("Verification should succeed: %v", err)
+	}
+	// checksum by path relative to repository without prefix
+	checksums := map[string]string{"foo/bar/parent.yml": strings.TrimPrefix(checksumFoo, ChecksumPrefix)}
+	if err := VerifyChecksum("parent", file, checksums, repo); err != nil {
+		t.Errorf("Verification should succeed: %v", err)
+	}
+	// bad checksum
+	checksums = map[string]string{"foo/bar/parent.yml": "sha256:1234"}
+	if err := VerifyChecksum("parent", file, checksums, repo); err == nil {
+		t.Errorf("Verification with bad checksum should fail")
+	}
+}
+
+func TestNewBuildIntegrity(t *testing.T) {
+	repo := "/tmp/neon"
+	if _, err := WriteFile(repo+"/foo/bar", "parent.yml", "doc: parent"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	dir := "/tmp/integrity"
+	build := "extends: foo/bar/parent.yml\nintegrity:\n  foo/bar/parent.yml: 'sha256:1234'\n"
+	if _, err := WriteFile(dir, "build.yml", build); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	_, err := NewBuild(dir+"/build.yml", dir, repo, false)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Loading tampered parent should fail: %v", err)
+	}
+	// lock file overwrites checksums in build file
+	checksum, err := Checksum(repo + "/foo/bar/parent.yml")
+	if err != nil {
+		t.Fatalf("computing checksum: %v", err)
+	}
+	if _, err := WriteFile(dir, LockFile, "foo/bar/parent.yml: '"+checksum+"'"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := NewBuild(dir+"/build.yml", dir, repo, false); err != nil {
+		t.Errorf("Loading parent with lock file should succeed: %v", err)
+	}
+}
+
+func TestWriteLockFile(t *testing.T) {
+	repo := "/tmp/neon"
+	if _, err := WriteFile(repo+"/foo/bar", "parent.yml", "context: foo/bar/script.ank"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := WriteFile(repo+"/foo/bar", "script.ank", "foo"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	dir := "/tmp/integrity"
+	if _, err := WriteFile(dir, "build.yml", "extends: foo/bar/parent.yml"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	build, err := NewBuild(dir+"/build.yml", dir, repo, false)
+	if err != nil {
+		t.Fatalf("loading build: %v", err)
+	}
+	path, err := build.WriteLockFile()
+	if err != nil {
+		t.Fatalf("writing lock file: %v", err)
+	}
+	checksums, err := LoadLockFile(dir)
+	Assert(err, nil, t)
+	Assert(path, dir+"/"+LockFile, t)
+	Assert(len(checksums), 2, t)
+	Assert(checksums["foo/bar/script.ank"], checksumFoo, t)
+}
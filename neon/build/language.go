@@ -0,0 +1,148 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+// DefaultLanguage is the language of expressions if build doesn't set one
+const DefaultLanguage = "anko"
+
+// Evaluator evaluates expressions of a language in the context of a build
+type Evaluator interface {
+	// Evaluate evaluates given expression:
+	// - context: the context holding properties
+	// - expression: the expression to evaluate
+	// Return:
+	// - the value of the expression
+	// - an error if something went wrong
+	Evaluate(context *Context, expression string) (interface{}, error)
+}
+
+// Languages maps language names to their evaluator
+var Languages = map[string]Evaluator{
+	"anko":  AnkoEvaluator{},
+	"typed": TypedEvaluator{},
+}
+
+// ParseLanguage parses language field of the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseLanguage(object util.Object, build *Build) error {
+	if object.HasField("language") {
+		language, err := object.GetString("language")
+		if err != nil {
+			return fmt.Errorf("getting language: %v", err)
+		}
+		if _, ok := Languages[language]; !ok {
+			return fmt.Errorf("unknown language '%s' (must be one of %s)", language,
+				strings.Join(LanguageNames(), ", "))
+		}
+		build.Language = language
+	}
+	return nil
+}
+
+// LanguageNames returns the sorted list of available languages
+// Return: language names
+func LanguageNames() []string {
+	var names []string
+	for name := range Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetLanguage returns the language of expressions, set in root build file
+// Return: the name of the language
+func (build *Build) GetLanguage() string {
+	if build != nil && build.root().Language != "" {
+		return build.root().Language
+	}
+	return DefaultLanguage
+}
+
+// checkLanguage checks that parents don't set another language
+// - language: the language of the build
+// Return: an error if a parent sets another language
+func (build *Build) checkLanguage(language string) error {
+	for _, parent := range build.Parents {
+		if parent.Language != "" && parent.Language != language {
+			return fmt.Errorf("parent build file '%s' uses language '%s' instead of '%s'",
+				parent.File, parent.Language, language)
+		}
+		if err := parent.checkLanguage(language); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnkoEvaluator evaluates Anko expressions
+type AnkoEvaluator struct{}
+
+// Evaluate evaluates given Anko expression:
+// - context: the context holding properties
+// - expression: the expression to evaluate
+// Return:
+// - the value of the expression
+// - an error if something went wrong
+func (evaluator AnkoEvaluator) Evaluate(context *Context, expression string) (interface{}, error) {
+	value, err := context.execute(expression)
+	if err != nil {
+		return nil, FormatScriptError(err)
+	}
+	return value, nil
+}
+
+// TypedEvaluator evaluates expressions of the typed language, which may
+// call builtins but no other function
+type TypedEvaluator struct{}
+
+// Evaluate evaluates given typed expression:
+// - context: the context holding properties
+// - expression: the expression to evaluate
+// Return:
+// - the value of the expression
+// - an error if something went wrong
+func (evaluator TypedEvaluator) Evaluate(context *Context, expression string) (interface{}, error) {
+	node, err := parseExpr(expression)
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression: %v", err)
+	}
+	return node.eval(exprContext{context: context})
+}
+
+// exprContext resolves properties and builtins in a build context
+type exprContext struct {
+	context *Context
+}
+
+func (scope exprContext) property(name string) (interface{}, error) {
+	if _, ok := BuiltinMap[name]; ok {
+		return nil, fmt.Errorf("builtin %s must be called", name)
+	}
+	value, err := scope.context.GetProperty(name)
+	if err != nil {
+		return nil, fmt.Errorf("undefined property '%s'", name)
+	}
+	return value, nil
+}
+
+func (scope exprContext) function(name string) (reflect.Value, error) {
+	if _, ok := BuiltinMap[name]; !ok {
+		return reflect.Value{}, fmt.Errorf("unknown builtin '%s'", name)
+	}
+	// builtins are taken from the VM where they may be disabled in safe mode
+	function, err := scope.context.GetProperty(name)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("unknown builtin '%s'", name)
+	}
+	return reflect.ValueOf(function), nil
+}
@@ -0,0 +1,197 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/mattn/anko/env"
+)
+
+// lazyState holds values of lazy properties, shared by a context and its
+// copies so that a property is evaluated only once
+type lazyState struct {
+	mutex      sync.Mutex
+	properties map[string]interface{}
+	values     map[string]reflect.Value
+	// evaluations in progress by property name
+	running map[string]*lazyRun
+	// property each lookup is waiting for
+	waiting map[*lazyLookup]string
+}
+
+// lazyRun is an evaluation of a lazy property in progress
+type lazyRun struct {
+	owner *lazyLookup
+	done  chan struct{}
+	err   error
+}
+
+// lazyLookup evaluates lazy properties on first access and memoizes their
+// values. It is called by the VM when a symbol is not defined. Each context
+// has its own lookup, so that properties are evaluated in calling context.
+type lazyLookup struct {
+	state   *lazyState
+	context *Context
+	err     error
+}
+
+// newLazyLookup builds a lookup for given lazy properties:
+// - context: the context to evaluate properties into
+// - properties: the raw values of lazy properties by name
+// Return: the lookup to set in the VM
+func newLazyLookup(context *Context, properties map[string]interface{}) *lazyLookup {
+	return &lazyLookup{
+		state: &lazyState{
+			properties: properties,
+			values:     make(map[string]reflect.Value),
+			running:    make(map[string]*lazyRun),
+			waiting:    make(map[*lazyLookup]string),
+		},
+		context: context,
+	}
+}
+
+// copy returns a lookup sharing lazy values that evaluates properties in
+// another context:
+// - context: the context to evaluate properties into
+// Return: the lookup to set in the VM of the context
+func (lookup *lazyLookup) copy(context *Context) *lazyLookup {
+	return &lazyLookup{state: lookup.state, context: context}
+}
+
+// Get returns the value of a lazy property, evaluating it on first access.
+// As the VM reports errors of lookups as undefined symbols, evaluation errors
+// are also recorded to be returned by Error.
+// - symbol: the name of the property
+// Return:
+// - the value of the property
+// - an error if symbol is not a lazy property or evaluation failed
+func (lookup *lazyLookup) Get(symbol string) (reflect.Value, error) {
+	value, err := lookup.get(symbol)
+	if err != nil {
+		lookup.state.mutex.Lock()
+		if _, ok := lookup.state.properties[symbol]; ok {
+			lookup.err = err
+		}
+		lookup.state.mutex.Unlock()
+	}
+	return value, err
+}
+
+func (lookup *lazyLookup) get(symbol string) (reflect.Value, error) {
+	state := lookup.state
+	state.mutex.Lock()
+	for {
+		if value, ok := state.values[symbol]; ok {
+			state.mutex.Unlock()
+			return value, nil
+		}
+		if _, ok := state.properties[symbol]; !ok {
+			state.mutex.Unlock()
+			return env.NilValue, fmt.Errorf("undefined symbol '%s'", symbol)
+		}
+		run, ok := state.running[symbol]
+		if !ok {
+			break
+		}
+		// property is being evaluated, by this lookup or another waiting
+		// for this one, or by another lookup we wait for
+		if state.waitsFor(run.owner, lookup) {
+			state.mutex.Unlock()
+			return env.NilValue, fmt.Errorf("lazy property '%s' references itself", symbol)
+		}
+		state.waiting[lookup] = symbol
+		state.mutex.Unlock()
+		<-run.done
+		state.mutex.Lock()
+		delete(state.waiting, lookup)
+		if run.err != nil {
+			state.mutex.Unlock()
+			return env.NilValue, run.err
+		}
+	}
+	run := &lazyRun{owner: lookup, done: make(chan struct{})}
+	state.running[symbol] = run
+	raw := state.properties[symbol]
+	state.mutex.Unlock()
+	value, err := lookup.evaluate(symbol, raw)
+	state.mutex.Lock()
+	delete(state.running, symbol)
+	if err == nil {
+		state.values[symbol] = value
+	}
+	run.err = err
+	close(run.done)
+	state.mutex.Unlock()
+	return value, err
+}
+
+// waitsFor tells if a lookup is, or waits for, given lookup. Must be called
+// with mutex locked.
+// - from: the lookup to start from
+// - to: the lookup to look for
+// Return: true if from is to or waits for it
+func (state *lazyState) waitsFor(from, to *lazyLookup) bool {
+	for lookup := from; lookup != nil; {
+		if lookup == to {
+			return true
+		}
+		symbol, ok := state.waiting[lookup]
+		if !ok {
+			return false
+		}
+		run, ok := state.running[symbol]
+		if !ok {
+			return false
+		}
+		lookup = run.owner
+	}
+	return false
+}
+
+// evaluate evaluates a lazy property in context of the lookup
+func (lookup *lazyLookup) evaluate(symbol string, raw interface{}) (reflect.Value, error) {
+	value, err := lookup.context.EvaluateObject(raw)
+	if err != nil {
+		return env.NilValue, fmt.Errorf("evaluating lazy property '%s': %v", symbol, err)
+	}
+	if declaration, ok := lookup.context.Build.GetDeclarations()[symbol]; ok {
+		if err := declaration.Validate(symbol, value, true); err != nil {
+			return env.NilValue, err
+		}
+	}
+	if value == nil {
+		return env.NilValue, nil
+	}
+	return reflect.ValueOf(value), nil
+}
+
+// Error returns and clears the last evaluation error
+// Return: the last error, nil if none
+func (lookup *lazyLookup) Error() error {
+	lookup.state.mutex.Lock()
+	defer lookup.state.mutex.Unlock()
+	err := lookup.err
+	lookup.err = nil
+	return err
+}
+
+// Type is not used for lazy properties
+// - symbol: the name of the type
+// Return: an error as lazy lookup doesn't define types
+func (lookup *lazyLookup) Type(symbol string) (reflect.Type, error) {
+	return nil, fmt.Errorf("undefined type '%s'", symbol)
+}
+
+// LazyProperties returns names of properties declared lazy
+// Return: the list of lazy property names
+func (build *Build) LazyProperties() []string {
+	var names []string
+	for name, declaration := range build.GetDeclarations() {
+		if declaration.Lazy {
+			names = append(names, name)
+		}
+	}
+	return names
+}
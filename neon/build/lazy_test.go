@@ -0,0 +1,139 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestLazyProperties(t *testing.T) {
+	build := &Build{
+		Properties: util.Object{
+			"COUNT":     0,
+			"EXPENSIVE": "=COUNT = COUNT + 1; 'value'",
+			"OTHER":     "={EXPENSIVE}-suffix",
+		},
+		Declarations: map[string]Declaration{
+			"EXPENSIVE": {Type: "string", Lazy: true},
+			"OTHER":     {Lazy: true},
+		},
+	}
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("init context: %v", err)
+	}
+	count, _ := context.GetProperty("COUNT")
+	Assert(count, 0, t)
+	value, err := context.EvaluateExpression("OTHER")
+	if err != nil {
+		t.Fatalf("evaluating lazy property: %v", err)
+	}
+	Assert(value, "value-suffix", t)
+	value, err = context.EvaluateExpression("EXPENSIVE")
+	if err != nil {
+		t.Fatalf("evaluating lazy property: %v", err)
+	}
+	Assert(value, "value", t)
+	count, _ = context.GetProperty("COUNT")
+	Assert(count, int64(1), t)
+}
+
+func TestLazyPropertiesErrors(t *testing.T) {
+	build := &Build{
+		Properties: util.Object{
+			"PORT": "='not a number'",
+			"SELF": "=SELF",
+		},
+		Declarations: map[string]Declaration{
+			"PORT": {Type: "integer", Lazy: true},
+			"SELF": {Lazy: true},
+		},
+	}
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("lazy properties should not be evaluated at init: %v", err)
+	}
+	_, err := context.EvaluateExpression("PORT")
+	if err == nil || err.Error() != "property 'PORT' must be of type integer" {
+		t.Errorf("bad lazy validation error: %v", err)
+	}
+	_, err = context.EvaluateExpression("SELF")
+	if err == nil || !strings.Contains(err.Error(), "lazy property 'SELF' references itself") {
+		t.Errorf("bad lazy self reference error: %v", err)
+	}
+}
+
+func TestLazyPropertiesThreads(t *testing.T) {
+	build := &Build{
+		Properties: util.Object{
+			"COUNT": 0,
+			"SLOW":  "=COUNT = COUNT + 1; time = import('time'); time.Sleep(50 * time.Millisecond); 'slow'",
+			"LOCAL": "=THREAD * 10",
+			"PING":  "=time = import('time'); time.Sleep(50 * time.Millisecond); PONG",
+			"PONG":  "=time = import('time'); time.Sleep(50 * time.Millisecond); PING",
+		},
+		Declarations: map[string]Declaration{
+			"SLOW":  {Lazy: true},
+			"LOCAL": {Lazy: true},
+			"PING":  {Lazy: true},
+			"PONG":  {Lazy: true},
+		},
+	}
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("init context: %v", err)
+	}
+	// concurrent readers wait for first evaluation, which runs in the
+	// context of the thread that reads the property first
+	threads := make([]*Context, 4)
+	errors := make([]error, len(threads))
+	var group sync.WaitGroup
+	for index := range threads {
+		threads[index] = context.Copy()
+		threads[index].SetProperty("THREAD", index+1)
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			value, err := threads[index].EvaluateExpression("SLOW")
+			if err == nil && value != "slow" {
+				err = fmt.Errorf("bad value %v", value)
+			}
+			errors[index] = err
+		}(index)
+	}
+	group.Wait()
+	total := int64(0)
+	for index, thread := range threads {
+		if errors[index] != nil {
+			t.Errorf("thread %d: %v", index, errors[index])
+		}
+		count, _ := thread.GetProperty("COUNT")
+		if count, ok := count.(int64); ok {
+			total += count
+		}
+	}
+	Assert(total, int64(1), t)
+	count, _ := context.GetProperty("COUNT")
+	Assert(count, 0, t)
+	// thread local properties are visible to lazy properties
+	value, err := threads[2].EvaluateExpression("LOCAL")
+	if err != nil {
+		t.Fatalf("evaluating lazy property in thread: %v", err)
+	}
+	Assert(value, int64(30), t)
+	// a reference cycle across threads fails instead of blocking
+	group.Add(2)
+	for index, name := range []string{"PING", "PONG"} {
+		go func(index int, name string) {
+			defer group.Done()
+			_, errors[index] = threads[index].EvaluateExpression(name)
+		}(index, name)
+	}
+	group.Wait()
+	if errors[0] == nil && errors[1] == nil {
+		t.Errorf("lazy properties referencing each other should fail")
+	}
+}
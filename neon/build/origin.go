@@ -0,0 +1,96 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Origin is the source of a property definition:
+// - Value: the value of the property, as written in the source
+// - File: the file or environment variable defining the property
+// - Source: the kind of source, such as properties or configuration
+type Origin struct {
+	Value  interface{}
+	File   string
+	Source string
+}
+
+// AddOrigin records a definition of a property:
+// - name: the name of the property
+// - origin: the source of the definition
+func (build *Build) AddOrigin(name string, origin Origin) {
+	if build.Origins == nil {
+		build.Origins = make(map[string][]Origin)
+	}
+	build.Origins[name] = append(build.Origins[name], origin)
+}
+
+// mergeOrigins prepends definitions of parents, which must have been merged
+// already, to those of the build, in the order GetProperties merges them
+func (build *Build) mergeOrigins() {
+	origins := make(map[string][]Origin)
+	for _, parent := range build.Parents {
+		for name, list := range parent.Origins {
+			origins[name] = append(origins[name], list...)
+		}
+	}
+	for name, list := range build.Origins {
+		origins[name] = append(origins[name], list...)
+	}
+	build.Origins = origins
+}
+
+// InfoOrigins returns the definitions of a property in order of precedence,
+// the last one being the value of the property. If context is not nil, the
+// evaluated value is printed too, and properties defined in context scripts
+// are found:
+// - name: the name of the property
+// - context: the initialized context, nil to get static information
+// Return:
+// - definitions of the property, one per line
+// - an error if property is not defined
+func (build *Build) InfoOrigins(name string, context *Context) (string, error) {
+	origins := build.Origins[name]
+	var value interface{}
+	var defined bool
+	if context != nil {
+		var err error
+		value, err = context.GetProperty(name)
+		defined = err == nil
+	}
+	if len(origins) == 0 && !defined {
+		return "", fmt.Errorf("property '%s' is not defined in build files, configuration or command line", name)
+	}
+	info := name + ":\n"
+	if len(origins) == 0 {
+		info += "* defined in context scripts\n"
+	}
+	for index, origin := range origins {
+		str, err := PropertyToString(origin.Value, true)
+		if err != nil {
+			return "", fmt.Errorf("formatting property '%s': %v", name, err)
+		}
+		var where string
+		switch origin.Source {
+		case "environment":
+			where = "from " + origin.File
+		case "props":
+			where = "on command line"
+		default:
+			where = "in " + origin.File
+		}
+		marker := "-"
+		if index == len(origins)-1 {
+			marker = "*"
+		}
+		info += fmt.Sprintf("%s %s %s (%s)\n", marker, Mask(str), where, origin.Source)
+	}
+	if defined {
+		str, err := PropertyToString(value, true)
+		if err != nil {
+			return "", fmt.Errorf("formatting property '%s': %v", name, err)
+		}
+		info += "= " + Mask(str) + "\n"
+	}
+	return strings.TrimSpace(info), nil
+}
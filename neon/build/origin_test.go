@@ -0,0 +1,59 @@
+package build
+
+import (
+	"os"
+	"testing"
+)
+
+func TestInfoOrigins(t *testing.T) {
+	dir := "/tmp/neon"
+	parent, err := WriteFile(dir, "parent.yml", "properties:\n  VERSION: '1.0'\n  NAME: 'parent'\n")
+	if err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	config, err := WriteFile(dir, "config.yml", "VERSION: '1.2'\n")
+	if err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	file, err := WriteFile(dir, "build.yml", "extends: "+parent+"\nconfiguration: config.yml\n"+
+		"properties:\n  VERSION: '1.1'\n")
+	if err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	build, err := NewBuild(file, dir, dir, false)
+	if err != nil {
+		t.Fatalf("loading build: %v", err)
+	}
+	_ = os.Setenv("NEON_PROP_VERSION", "2.0")
+	defer func() {
+		_ = os.Unsetenv("NEON_PROP_VERSION")
+	}()
+	if err := build.SetCommandLineProperties("{VERSION: '2.1'}", ""); err != nil {
+		t.Fatalf("setting properties: %v", err)
+	}
+	info, err := build.InfoOrigins("VERSION", nil)
+	if err != nil {
+		t.Fatalf("getting origins: %v", err)
+	}
+	Assert(info, `VERSION:
+- "1.0" in /tmp/neon/parent.yml (properties)
+- "1.1" in /tmp/neon/build.yml (properties)
+- "1.2" in `+config+` (configuration)
+- "2.0" from NEON_PROP_VERSION (environment)
+* "2.1" on command line (props)`, t)
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	info, err = build.InfoOrigins("NAME", context)
+	if err != nil {
+		t.Fatalf("getting origins: %v", err)
+	}
+	Assert(info, "NAME:\n* \"parent\" in /tmp/neon/parent.yml (properties)\n= \"parent\"", t)
+	if _, err := build.InfoOrigins("MISSING", nil); err == nil {
+		t.Errorf("undefined property should fail")
+	}
+}
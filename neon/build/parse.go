@@ -165,11 +165,15 @@ func ParseProperties(object util.Object, build *Build) error {
 			return fmt.Errorf("parsing properties: %v", err)
 		}
 	}
+	for name, value := range properties {
+		build.AddOrigin(name, Origin{Value: value, File: filepath.Join(build.Dir, build.File), Source: "properties"})
+	}
 	build.Properties = properties
 	return nil
 }
 
-// ParseConfiguration parses configuration field of the build:
+// ParseConfiguration parses configuration field of the build. Encrypted
+// configuration files are decrypted in memory:
 // - object: the object to parse
 // - build: build that is being constructed
 // Return: an error if something went wrong
@@ -185,7 +189,7 @@ func ParseConfiguration(object util.Object, build *Build) error {
 			if !filepath.IsAbs(file) {
 				file = filepath.Join(build.Dir, file)
 			}
-			source, err := util.ReadFile(file)
+			source, err := ReadConfigurationFile(file)
 			if err != nil {
 				return fmt.Errorf("reading configuration file: %v", err)
 			}
@@ -195,6 +199,7 @@ func ParseConfiguration(object util.Object, build *Build) error {
 			}
 			for name, value := range config {
 				build.Properties[name] = value
+				build.AddOrigin(name, Origin{Value: value, File: file, Source: "configuration"})
 			}
 		}
 		build.Config = files
@@ -280,6 +285,23 @@ func ParseTargets(object util.Object, build *Build) error {
 	return nil
 }
 
+// ParseIntegrity parses checksums of repository files used by the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseIntegrity(object util.Object, build *Build) error {
+	integrity := make(map[string]string)
+	if object.HasField("integrity") {
+		checksums, err := object.GetMapStringString("integrity")
+		if err != nil {
+			return fmt.Errorf("getting integrity: %v", err)
+		}
+		integrity = checksums
+	}
+	build.Integrity = integrity
+	return nil
+}
+
 // ParseVersion parses NeON version requirement of the build:
 // - object: the object to parse
 // - build: build that is being constructed
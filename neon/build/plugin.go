@@ -0,0 +1,251 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+// FindPlugins finds plugins installed in given repository.
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - list of plugin names (such as "c4s4/build").
+// - error if something went wrong.
+func FindPlugins(repository string) ([]string, error) {
+	files, err := util.FindFiles(repository, []string{"*/*"}, ExcludeCache, true)
+	if err != nil {
+		return nil, err
+	}
+	var plugins []string
+	for _, file := range files {
+		if util.DirExists(filepath.Join(repository, file)) {
+			plugins = append(plugins, util.PathToUnix(file))
+		}
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+// FindScripts finds context scripts in given repository.
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - list of script files relative to repo.
+// - error if something went wrong.
+func FindScripts(repository string) ([]string, error) {
+	files, err := util.FindFiles(repository, []string{"*/*/*.ank"}, ExcludeCache, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(files); i++ {
+		files[i] = util.PathToUnix(files[i])
+	}
+	return files, nil
+}
+
+// PluginRevision returns git branch and revision of given plugin:
+// - plugin: the plugin name (such as "c4s4/build").
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - branch name (such as "master").
+// - short revision hash (such as "1a2b3c4").
+// - error if plugin is not a git repository.
+func PluginRevision(plugin, repository string) (string, string, error) {
+	dir := filepath.Join(repository, plugin)
+	if !util.DirExists(filepath.Join(dir, ".git")) {
+		return "", "", fmt.Errorf("plugin '%s' is not a git repository", plugin)
+	}
+	branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("getting branch for plugin '%s': %v", plugin, err)
+	}
+	revision, err := gitOutput(dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("getting revision for plugin '%s': %v", plugin, err)
+	}
+	return branch, revision, nil
+}
+
+// PluginBehind fetches remote of given plugin and returns the number of
+// commits local branch is behind remote one:
+// - plugin: the plugin name (such as "c4s4/build").
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - number of commits plugin is behind its remote.
+// - error if something went wrong.
+func PluginBehind(plugin, repository string) (int, error) {
+	dir := filepath.Join(repository, plugin)
+	if _, err := gitOutput(dir, "fetch"); err != nil {
+		return 0, fmt.Errorf("fetching plugin '%s': %v", plugin, err)
+	}
+	branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return 0, fmt.Errorf("getting branch for plugin '%s': %v", plugin, err)
+	}
+	count, err := gitOutput(dir, "rev-list", "--count", "HEAD..origin/"+branch)
+	if err != nil {
+		return 0, fmt.Errorf("comparing plugin '%s' with remote: %v", plugin, err)
+	}
+	var behind int
+	if _, err := fmt.Sscanf(count, "%d", &behind); err != nil {
+		return 0, fmt.Errorf("parsing commits count '%s': %v", count, err)
+	}
+	return behind, nil
+}
+
+// RemovePlugin removes given plugin from repository:
+// - plugin: the plugin name (such as "c4s4/build").
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return: an error if something went wrong.
+func RemovePlugin(plugin, repository string) error {
+	if err := checkPluginName(plugin); err != nil {
+		return err
+	}
+	dir := filepath.Join(repository, plugin)
+	if !util.DirExists(dir) {
+		return fmt.Errorf("plugin '%s' is not installed", plugin)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing plugin '%s': %v", plugin, err)
+	}
+	// remove user directory if empty
+	parent := filepath.Dir(dir)
+	if entries, err := os.ReadDir(parent); err == nil && len(entries) == 0 {
+		_ = os.Remove(parent)
+	}
+	MessageArgs("Plugin '%s' removed from '%s'", plugin, repository)
+	return nil
+}
+
+// InfoPlugins generates list of plugins in repository with their revision:
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return: plugins info as a string
+func InfoPlugins(repository string) string {
+	plugins, err := FindPlugins(repository)
+	if err != nil {
+		panic(err)
+	}
+	info := ""
+	for _, plugin := range plugins {
+		branch, revision, err := PluginRevision(plugin, repository)
+		if err != nil {
+			info += plugin + "\n"
+		} else {
+			info += fmt.Sprintf("%s [%s %s]\n", plugin, branch, revision)
+		}
+	}
+	return strings.TrimSpace(info)
+}
+
+// InfoPlugin generates information about given plugin, that is parent build
+// files, templates and scripts it provides:
+// - plugin: the plugin name (such as "c4s4/build").
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return: plugin info as a string and an error if plugin was not found
+func InfoPlugin(plugin, repository string) (string, error) {
+	if err := checkPluginName(plugin); err != nil {
+		return "", err
+	}
+	if !util.DirExists(filepath.Join(repository, plugin)) {
+		return "", fmt.Errorf("plugin '%s' is not installed", plugin)
+	}
+	info := "plugin: " + plugin + "\n"
+	if branch, revision, err := PluginRevision(plugin, repository); err == nil {
+		info += "revision: " + branch + " " + revision + "\n"
+	}
+	parents, err := FindParents(repository)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, file := range parents {
+		name := path.Base(file)
+		if name != "CHANGELOG.yml" && name != "build.yml" {
+			files = append(files, file)
+		}
+	}
+	info += infoPluginFiles("parents", plugin, files)
+	templates, err := FindTemplates(repository)
+	if err != nil {
+		return "", err
+	}
+	info += infoPluginFiles("templates", plugin, templates)
+	scripts, err := FindScripts(repository)
+	if err != nil {
+		return "", err
+	}
+	info += infoPluginFiles("scripts", plugin, scripts)
+	return strings.TrimSpace(info), nil
+}
+
+// InfoOutdated generates list of plugins that are behind their remote, with
+// errors for plugins that couldn't be compared with their remote:
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return: outdated plugins info as a string and an error if any
+func InfoOutdated(repository string) (string, error) {
+	plugins, err := FindPlugins(repository)
+	if err != nil {
+		return "", fmt.Errorf("searching plugins: %v", err)
+	}
+	info := ""
+	for _, plugin := range plugins {
+		if !util.DirExists(filepath.Join(repository, plugin, ".git")) {
+			continue
+		}
+		behind, err := PluginBehind(plugin, repository)
+		if err != nil {
+			info += err.Error() + "\n"
+			continue
+		}
+		if behind > 0 {
+			info += fmt.Sprintf("%s: %d commit(s) behind\n", plugin, behind)
+		}
+	}
+	if info == "" {
+		return "All plugins are up to date", nil
+	}
+	return strings.TrimSpace(info), nil
+}
+
+// checkPluginName checks that plugin name is valid, such as "c4s4/build"
+func checkPluginName(plugin string) error {
+	re := regexp.MustCompile(`^` + RegexpPlugin + `$`)
+	if !re.MatchString(plugin) {
+		return fmt.Errorf("plugin name '%s' is invalid", plugin)
+	}
+	return nil
+}
+
+func infoPluginFiles(title, plugin string, files []string) string {
+	info := ""
+	for _, file := range files {
+		if strings.HasPrefix(file, plugin+"/") {
+			info += "- " + file + "\n"
+		}
+	}
+	if info != "" {
+		info = title + ":\n" + info
+	}
+	return info
+}
+
+// gitOutput runs git command in given directory and returns trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	bytes, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(bytes))
+	if err != nil {
+		if output != "" {
+			return "", fmt.Errorf("%v: %s", err, output)
+		}
+		return "", err
+	}
+	return output, nil
+}
@@ -0,0 +1,157 @@
+package build
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestFindPlugins(t *testing.T) {
+	repo := "/tmp/neon"
+	if _, err := WriteFile(repo+"/foo/bar", "parent.yml", ""); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := WriteFile(repo+"/foo/spam", "parent.yml", ""); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	plugins, err := FindPlugins(repo)
+	if err != nil {
+		t.Errorf("Error finding plugins: %v", err)
+	}
+	if !reflect.DeepEqual(plugins, []string{"foo/bar", "foo/spam"}) {
+		t.Errorf("Bad plugins: %v", plugins)
+	}
+}
+
+func TestRemovePlugin(t *testing.T) {
+	repo := "/tmp/neon"
+	if _, err := WriteFile(repo+"/foo/bar", "parent.yml", ""); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	if err := RemovePlugin("foo/bar", repo); err != nil {
+		t.Errorf("Error removing plugin: %v", err)
+	}
+	if util.DirExists(filepath.Join(repo, "foo")) {
+		t.Errorf("Plugin directory was not removed")
+	}
+	if err := RemovePlugin("foo/bar", repo); err == nil {
+		t.Errorf("Removing missing plugin should fail")
+	}
+	if err := RemovePlugin("../foo", repo); err == nil {
+		t.Errorf("Removing invalid plugin should fail")
+	}
+}
+
+func TestInfoPlugin(t *testing.T) {
+	repo := "/tmp/neon"
+	for _, file := range []string{"parent.yml", "build.yml", "template.tpl", "script.ank"} {
+		if _, err := WriteFile(repo+"/foo/bar", file, ""); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	if _, err := WriteFile(repo+"/foo/spam", "other.yml", ""); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	info, err := InfoPlugin("foo/bar", repo)
+	if err != nil {
+		t.Errorf("Error getting plugin info: %v", err)
+	}
+	expected := `plugin: foo/bar
+parents:
+- foo/bar/parent.yml
+templates:
+- foo/bar/template.tpl
+scripts:
+- foo/bar/script.ank`
+	if info != expected {
+		t.Errorf("Bad plugin info: %s", info)
+	}
+	if _, err := InfoPlugin("foo/eggs", repo); err == nil {
+		t.Errorf("Info on missing plugin should fail")
+	}
+	if _, err := InfoPlugin("foo/../foo/bar", repo); err == nil {
+		t.Errorf("Info on plugin with invalid name should fail")
+	}
+}
+
+func TestInfoOutdated(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	git := func(dir string, args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("running git %v: %s", args, output)
+		}
+	}
+	// plugin that can't be fetched comes first
+	origin := filepath.Join(dir, "origin")
+	for _, path := range []string{origin, filepath.Join(repo, "bar", "broken"), filepath.Join(repo, "foo")} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{origin, filepath.Join(repo, "bar", "broken")} {
+		git(path, "init", "-q", "-b", "master")
+		git(path, "commit", "-q", "--allow-empty", "-m", "first")
+	}
+	git(filepath.Join(repo, "bar", "broken"), "remote", "add", "origin", filepath.Join(dir, "missing"))
+	git(filepath.Join(repo, "foo"), "clone", "-q", origin, "outdated")
+	git(origin, "commit", "-q", "--allow-empty", "-m", "second")
+	info, err := InfoOutdated(repo)
+	if err != nil {
+		t.Fatalf("getting outdated plugins: %v", err)
+	}
+	if !strings.Contains(info, "fetching plugin 'bar/broken'") ||
+		!strings.Contains(info, "foo/outdated: 1 commit(s) behind") {
+		t.Errorf("bad outdated plugins info: %s", info)
+	}
+}
+
+func TestPluginRevision(t *testing.T) {
+	repo := "/tmp/neon"
+	dir, err := WriteFile(repo+"/foo/bar", "parent.yml", "")
+	if err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	if _, _, err := PluginRevision("foo/bar", repo); err == nil {
+		t.Errorf("Revision of plugin that is not a git repository should fail")
+	}
+	dir = filepath.Dir(dir)
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "master"},
+		{"add", "parent.yml"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "test"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("running git %v: %s", args, output)
+		}
+	}
+	branch, revision, err := PluginRevision("foo/bar", repo)
+	if err != nil {
+		t.Errorf("Error getting plugin revision: %v", err)
+	}
+	if branch != "master" || len(revision) < 7 {
+		t.Errorf("Bad plugin revision: %s %s", branch, revision)
+	}
+}
@@ -22,19 +22,20 @@ var colorError colorizer
 // Message prints a message on console:
 // - text: text to print (that might embed fields to print, such as "%s")
 func Message(text string) {
-	printGray(text)
+	printGray(Mask(text))
 }
 
 // MessageArgs prints a message on console:
 // - text: text to print (that might embed fields to print, such as "%s")
 // - args: arguments for the text to print
 func MessageArgs(text string, args ...interface{}) {
-	printGrayArgs(text, args...)
+	printGray(Mask(fmt.Sprintf(text, args...)))
 }
 
 // Info prints an information message on console:
 // - text: text to print (that might embed fields to print, such as "%s")
 func Info(text string) {
+	text = Mask(text)
 	if Gray {
 		printGray(text)
 	} else {
@@ -46,16 +47,13 @@ func Info(text string) {
 // - text: text to print (that might embed fields to print, such as "%s")
 // - args: arguments for the text to print
 func InfoArgs(text string, args ...interface{}) {
-	if Gray {
-		printGrayArgs(text, args...)
-	} else {
-		printColorArgs(colorTitle(text), args...)
-	}
+	Info(fmt.Sprintf(text, args...))
 }
 
 // Title prints a title on the console
 // - text: text of the title to print
 func Title(text string) {
+	text = Mask(text)
 	length := util.TerminalWidth() - (4 + utf8.RuneCountInString(text))
 	if length < 2 {
 		length = 2
@@ -81,6 +79,7 @@ func PrintOk() {
 // text
 // - text: the explanatory text to print
 func PrintError(text string) {
+	text = Mask(text)
 	if Gray {
 		printGrayArgs("ERROR %s", text)
 	} else {
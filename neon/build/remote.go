@@ -0,0 +1,115 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+const (
+	// RemoteCache is the directory in repository where remote files are cached
+	RemoteCache = ".cache"
+	// RemoteTimeout is the timeout for fetching remote files
+	RemoteTimeout = 30 * time.Second
+)
+
+// ExcludeCache is the glob to exclude remote files cache from repository
+// searches
+var ExcludeCache = []string{RemoteCache + "/**/*"}
+
+// IsRemote tells if given file name is a remote URL
+// - name: the file name
+// Return: a boolean that tells if name is an HTTP or HTTPS URL
+func IsRemote(name string) bool {
+	return strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://")
+}
+
+// RemotePath returns path of cached remote file in repository:
+// - address: the URL of the remote file
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - path of the cached file (such as ~/.neon/.cache/example.com/build/foo.yml)
+// - an error if URL is invalid
+func RemotePath(address, repository string) (string, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("parsing URL '%s': %v", address, err)
+	}
+	if parsed.Host == "" || parsed.Path == "" || strings.HasSuffix(parsed.Path, "/") {
+		return "", fmt.Errorf("URL '%s' doesn't point to a file", address)
+	}
+	host := strings.ReplaceAll(parsed.Host, ":", "_")
+	path := filepath.Clean(filepath.FromSlash(parsed.Path))
+	return filepath.Join(util.ExpandUserHome(repository), RemoteCache, host, path), nil
+}
+
+// FetchRemote fetches remote file and caches it in repository. If file is
+// already in cache, it is revalidated with its ETag. If server can't be
+// reached, cached file is used.
+// - address: the URL of the remote file
+// - repository: the NeON repository (defaults to '~/.neon')
+// Return:
+// - path of the cached file
+// - an error if something went wrong
+func FetchRemote(address, repository string) (string, error) {
+	path, err := RemotePath(address, repository)
+	if err != nil {
+		return "", err
+	}
+	etagFile := path + ".etag"
+	request, err := http.NewRequest("GET", address, nil)
+	if err != nil {
+		return "", fmt.Errorf("building request: %v", err)
+	}
+	if util.FileExists(path) && util.FileExists(etagFile) {
+		etag, err := os.ReadFile(etagFile)
+		if err == nil {
+			request.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+		}
+	}
+	client := &http.Client{Timeout: RemoteTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		if util.FileExists(path) {
+			return path, nil
+		}
+		return "", fmt.Errorf("getting '%s': %v", address, err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode == http.StatusNotModified && util.FileExists(path) {
+		return path, nil
+	}
+	if response.StatusCode >= 500 && util.FileExists(path) {
+		return path, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting '%s': %s", address, response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading '%s': %v", address, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), util.DirFileMode); err != nil {
+		return "", fmt.Errorf("creating cache directory: %v", err)
+	}
+	if err := os.WriteFile(path, body, util.FileMode); err != nil {
+		return "", fmt.Errorf("writing cached file '%s': %v", path, err)
+	}
+	if etag := response.Header.Get("ETag"); etag != "" {
+		if err := os.WriteFile(etagFile, []byte(etag), util.FileMode); err != nil {
+			return "", fmt.Errorf("writing ETag file '%s': %v", etagFile, err)
+		}
+	} else {
+		_ = os.Remove(etagFile)
+	}
+	return path, nil
+}
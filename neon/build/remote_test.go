@@ -0,0 +1,89 @@
+package build
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestRemotePath(t *testing.T) {
+	path, err := RemotePath("https://example.com:8080/build/foo.yml", "/tmp/neon")
+	Assert(err, nil, t)
+	Assert(path, "/tmp/neon/.cache/example.com_8080/build/foo.yml", t)
+	_, err = RemotePath("https://example.com/build/", "/tmp/neon")
+	if err == nil {
+		t.Errorf("URL without file should fail")
+	}
+}
+
+func TestFetchRemote(t *testing.T) {
+	repo := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("doc: remote parent"))
+	}))
+	url := server.URL + "/build/parent.yml"
+	// first fetch downloads the file
+	path, err := FetchRemote(url, repo)
+	Assert(err, nil, t)
+	content, err := os.ReadFile(path)
+	Assert(err, nil, t)
+	Assert(string(content), "doc: remote parent", t)
+	// second fetch revalidates the cached file
+	_, err = FetchRemote(url, repo)
+	Assert(err, nil, t)
+	Assert(requests, 2, t)
+	// cached file is used when server is unreachable
+	server.Close()
+	cached, err := FetchRemote(url, repo)
+	Assert(err, nil, t)
+	Assert(cached, path, t)
+	// cached files are not listed as parents or plugins
+	parents, err := FindParents(repo)
+	Assert(err, nil, t)
+	Assert(len(parents), 0, t)
+	plugins, err := FindPlugins(repo)
+	Assert(err, nil, t)
+	Assert(len(plugins), 0, t)
+}
+
+func TestFetchRemoteNotFound(t *testing.T) {
+	repo := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := FetchRemote(server.URL+"/build/parent.yml", repo); err == nil {
+		t.Errorf("fetching missing file should fail")
+	}
+}
+
+func TestParentPathRemote(t *testing.T) {
+	repo := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(repo)
+	}()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("doc: remote parent"))
+	}))
+	defer server.Close()
+	build := &Build{Repository: repo}
+	path, err := build.ParentPath(server.URL + "/build/parent.yml")
+	Assert(err, nil, t)
+	if !util.FileExists(path) {
+		t.Errorf("remote parent was not cached")
+	}
+}
@@ -3,6 +3,7 @@ package build
 import (
 	"fmt"
 	"github.com/c4s4/neon/neon/util"
+	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
@@ -16,6 +17,9 @@ const (
 	PluginSite = "github.com"
 )
 
+// RegexpGitURL is regexp for a git repository URL
+var RegexpGitURL = regexp.MustCompile(`^([a-z][a-z0-9+.-]*://|[\w.-]+@[\w.-]+:)`)
+
 // RegexpParentName is regexp for parent name
 var RegexpParentName = regexp.MustCompile(`[^/]+/[^/]+/[^/]+.yml`)
 
@@ -34,7 +38,7 @@ var RegexpLinkName = regexp.MustCompile(`[^/]+/[^/]+/[^/]+.yml`)
 // - list of parent build files relative to repo.
 // - error if something went wrong.
 func FindParents(repository string) ([]string, error) {
-	files, err := util.FindFiles(repository, []string{"*/*/*.yml"}, nil, false)
+	files, err := util.FindFiles(repository, []string{"*/*/*.yml"}, ExcludeCache, false)
 	if err != nil {
 		return nil, err
 	}
@@ -68,11 +72,14 @@ func FindParent(parent, repository string) ([]string, error) {
 }
 
 // ParentPath returns file path for plugin with given name.
-// - name: the name of the plugin (as "c4s4/build/foo.yml" or "foo")
+// - name: the name of the plugin (as "c4s4/build/foo.yml", "foo" or an URL)
 // Return:
 // - the plugin path as a string (as /home/casa/.neon/c4s4/build/foo.yml)
 // - error if something went wrong
 func (build *Build) ParentPath(name string) (string, error) {
+	if IsRemote(name) {
+		return FetchRemote(name, build.Repository)
+	}
 	if path.IsAbs(name) {
 		return name, nil
 	}
@@ -94,43 +101,136 @@ func (build *Build) ParentPath(name string) (string, error) {
 }
 
 // InstallPlugin installs given plugin in repository:
-//   - plugin: the plugin name such as c4s4/build. First part us Github user name
-//     and second is repository name for the plugin.
+//   - plugin: the plugin to install. This might be a name such as c4s4/build
+//     (first part is user name and second is repository name on default site),
+//     a git URL (such as https://git.example.com/team/build.git) or a local
+//     directory (such as ~/dev/build).
 //   - repository: plugin repository, defaults to ~/.neon.
+//   - site: default site for plugin names, defaults to github.com.
 //
 // Return: an error if something went wrong downloading plugin.
-func InstallPlugin(plugin, repository string) error {
-	re := regexp.MustCompile(`^` + RegexpPlugin + `$`)
-	if !re.MatchString(plugin) {
-		return fmt.Errorf("plugin name '%s' is invalid", plugin)
+func InstallPlugin(plugin, repository, site string) error {
+	source, name, err := PluginSource(plugin, site)
+	if err != nil {
+		return err
 	}
-	pluginPath := filepath.Join(repository, plugin)
+	pluginPath := filepath.Join(repository, name)
 	if util.DirExists(pluginPath) {
-		MessageArgs("Plugin '%s' already installed in '%s'", plugin, pluginPath)
+		MessageArgs("Plugin '%s' already installed in '%s'", name, pluginPath)
+		return nil
+	}
+	if util.DirExists(source) && !util.DirExists(filepath.Join(source, ".git")) {
+		MessageArgs("Copying directory '%s'...", source)
+		files, err := util.FindFiles(source, []string{"**/*"}, nil, false)
+		if err != nil {
+			return fmt.Errorf("installing plugin '%s': %v", plugin, err)
+		}
+		if err := os.MkdirAll(pluginPath, util.DirFileMode); err != nil {
+			return fmt.Errorf("installing plugin '%s': %v", plugin, err)
+		}
+		if err := util.CopyFilesToDir(source, files, pluginPath, false); err != nil {
+			return fmt.Errorf("installing plugin '%s': %v", plugin, err)
+		}
+		MessageArgs("Plugin '%s' installed in '%s'", name, pluginPath)
 		return nil
 	}
-	gitRepository := "https://" + PluginSite + "/" + plugin + ".git"
-	command := exec.Command("git", "clone", gitRepository, pluginPath)
+	command := exec.Command("git", "clone", source, pluginPath)
 	MessageArgs("Running command '%s'...", strings.Join(command.Args, " "))
 	output, err := command.CombinedOutput()
 	if err != nil {
-		re = regexp.MustCompile("\n\n")
+		re := regexp.MustCompile("\n\n")
 		message := re.ReplaceAllString(string(output), "\n")
 		message = strings.TrimSpace(message)
 		Message(message)
 		return fmt.Errorf("installing plugin '%s'", plugin)
 	}
-	MessageArgs("Plugin '%s' installed in '%s'", plugin, pluginPath)
+	MessageArgs("Plugin '%s' installed in '%s'", name, pluginPath)
 	return nil
 }
 
+// PluginSource returns source to clone and name for given plugin:
+// - plugin: plugin name (such as c4s4/build), git URL or local directory.
+// - site: default site for plugin names (such as github.com).
+// Return:
+// - source to clone (such as https://github.com/c4s4/build.git).
+// - name of the plugin in repository (such as c4s4/build).
+// - error if plugin is invalid.
+func PluginSource(plugin, site string) (string, string, error) {
+	if isLocalPlugin(plugin) {
+		dir, err := filepath.Abs(util.ExpandUserHome(plugin))
+		if err != nil {
+			return "", "", fmt.Errorf("getting plugin directory '%s': %v", plugin, err)
+		}
+		if !util.DirExists(dir) {
+			return "", "", fmt.Errorf("plugin directory '%s' was not found", plugin)
+		}
+		name, err := pluginName(filepath.ToSlash(dir))
+		if err != nil {
+			return "", "", fmt.Errorf("plugin directory '%s' is invalid", plugin)
+		}
+		return dir, name, nil
+	}
+	if RegexpGitURL.MatchString(plugin) {
+		name, err := pluginName(plugin)
+		if err != nil {
+			return "", "", fmt.Errorf("plugin URL '%s' is invalid", plugin)
+		}
+		return plugin, name, nil
+	}
+	re := regexp.MustCompile(`^` + RegexpPlugin + `$`)
+	if !re.MatchString(plugin) {
+		return "", "", fmt.Errorf("plugin name '%s' is invalid", plugin)
+	}
+	return SiteURL(site) + "/" + plugin + ".git", plugin, nil
+}
+
+// SiteURL returns base URL for given plugin site:
+//   - site: the site (such as 'github.com' or 'https://git.example.com/'),
+//     defaults to 'github.com'.
+//
+// Return: site URL (such as 'https://github.com').
+func SiteURL(site string) string {
+	if site == "" {
+		site = PluginSite
+	}
+	if !strings.Contains(site, "://") {
+		site = "https://" + site
+	}
+	return strings.TrimRight(site, "/")
+}
+
+// isLocalPlugin tells if given plugin is a path to a local directory
+func isLocalPlugin(plugin string) bool {
+	return filepath.IsAbs(plugin) ||
+		strings.HasPrefix(plugin, "./") ||
+		strings.HasPrefix(plugin, "../") ||
+		strings.HasPrefix(plugin, "~/")
+}
+
+// pluginName returns plugin name with last two parts of given path or URL
+func pluginName(source string) (string, error) {
+	source = strings.TrimSuffix(strings.TrimRight(source, "/"), ".git")
+	parts := strings.FieldsFunc(source, func(r rune) bool {
+		return r == '/' || r == ':'
+	})
+	if len(parts) < 2 {
+		return "", fmt.Errorf("no plugin name found in '%s'", source)
+	}
+	name := parts[len(parts)-2] + "/" + parts[len(parts)-1]
+	re := regexp.MustCompile(`^` + RegexpPlugin + `$`)
+	if !re.MatchString(name) {
+		return "", fmt.Errorf("bad plugin name '%s'", name)
+	}
+	return name, nil
+}
+
 // FindTemplates finds templates in given repository.
 // - repository: the NeON repository (defaults to '~/.neon')
 // Return:
 // - list of template files relative to repo.
 // - error if something went wrong.
 func FindTemplates(repository string) ([]string, error) {
-	files, err := util.FindFiles(repository, []string{"*/*/*.tpl"}, nil, false)
+	files, err := util.FindFiles(repository, []string{"*/*/*.tpl"}, ExcludeCache, false)
 	if err != nil {
 		return nil, err
 	}
@@ -201,11 +301,14 @@ func LinkPath(name, repository string) string {
 }
 
 // ScriptPath returns file path for script with given name.
-// - name: the name of the script (as "c4s4/build/foo.ank")
+// - name: the name of the script (as "c4s4/build/foo.ank" or an URL)
 // Return:
 // - the script path as a string (as /home/casa/.neon/c4s4/build/foo.ank)
 // - error if something went wrong
 func (build *Build) ScriptPath(name string) (string, error) {
+	if IsRemote(name) {
+		return FetchRemote(name, build.Repository)
+	}
 	if path.IsAbs(name) {
 		return name, nil
 	}
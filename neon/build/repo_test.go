@@ -86,7 +86,7 @@ func TestInstallPlugin(t *testing.T) {
 	defer func() {
 		_ = os.RemoveAll(repo)
 	}()
-	err := InstallPlugin("c4s4/build", repo)
+	err := InstallPlugin("c4s4/build", repo, "")
 	if err != nil {
 		t.Errorf("Error installing pluging: %v", err)
 	}
@@ -96,6 +96,59 @@ func TestInstallPlugin(t *testing.T) {
 	}
 }
 
+func TestInstallPluginLocal(t *testing.T) {
+	repo := "/tmp/neon"
+	source := "/tmp/plugins/foo/bar"
+	if _, err := WriteFile(source, "parent.yml", "doc: Parent"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(repo)
+		_ = os.RemoveAll("/tmp/plugins")
+	}()
+	if err := InstallPlugin(source, repo, ""); err != nil {
+		t.Errorf("Error installing plugin: %v", err)
+	}
+	if !util.FileExists(filepath.Join(repo, "foo/bar/parent.yml")) {
+		t.Errorf("Plugin file not found")
+	}
+}
+
+func TestPluginSource(t *testing.T) {
+	source, name, err := PluginSource("c4s4/build", "")
+	Assert(err, nil, t)
+	Assert(source, "https://github.com/c4s4/build.git", t)
+	Assert(name, "c4s4/build", t)
+	source, name, err = PluginSource("c4s4/build", "git.example.com")
+	Assert(err, nil, t)
+	Assert(source, "https://git.example.com/c4s4/build.git", t)
+	Assert(name, "c4s4/build", t)
+	source, name, err = PluginSource("https://git.example.com/group/team/build.git", "")
+	Assert(err, nil, t)
+	Assert(source, "https://git.example.com/group/team/build.git", t)
+	Assert(name, "team/build", t)
+	source, name, err = PluginSource("git@git.example.com:team/build.git", "")
+	Assert(err, nil, t)
+	Assert(source, "git@git.example.com:team/build.git", t)
+	Assert(name, "team/build", t)
+	source, name, err = PluginSource("file:///srv/git/team/build", "")
+	Assert(err, nil, t)
+	Assert(source, "file:///srv/git/team/build", t)
+	Assert(name, "team/build", t)
+	if _, _, err = PluginSource("foo", ""); err == nil {
+		t.Errorf("Invalid plugin name should fail")
+	}
+	if _, _, err = PluginSource("/tmp/not/existing/plugin", ""); err == nil {
+		t.Errorf("Missing plugin directory should fail")
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	Assert(SiteURL(""), "https://github.com", t)
+	Assert(SiteURL("git.example.com"), "https://git.example.com", t)
+	Assert(SiteURL("http://git.example.com/"), "http://git.example.com", t)
+}
+
 func TestFindTemplates(t *testing.T) {
 	repo := "/tmp/neon"
 	if _, err := WriteFile(repo+"/foo/bar", "template1.tpl", ""); err != nil {
@@ -0,0 +1,146 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/c4s4/neon/neon/util"
+	"github.com/mattn/anko/ast"
+	"github.com/mattn/anko/ast/astutil"
+	"github.com/mattn/anko/env"
+	"github.com/mattn/anko/parser"
+	"github.com/mattn/anko/vm"
+)
+
+// SafeMode tells if build files are untrusted (set with -safe option)
+var SafeMode = false
+
+// SafePackages is the list of packages that may be imported in safe mode
+var SafePackages = []string{"bytes", "encoding/json", "errors", "fmt", "math",
+	"math/big", "math/rand", "net/url", "path", "regexp", "sort", "strconv",
+	"strings", "time"}
+
+// unsafeFunctions is the list of Anko core functions disabled in safe mode
+var unsafeFunctions = []string{"load"}
+
+// ParseSafe parses safe field of the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseSafe(object util.Object, build *Build) error {
+	build.Safe = false
+	build.SafePackages = nil
+	if !object.HasField("safe") {
+		return nil
+	}
+	if safe, err := object.GetBoolean("safe"); err == nil {
+		build.Safe = safe
+		return nil
+	}
+	packages, err := object.GetListStrings("safe")
+	if err != nil {
+		return fmt.Errorf("getting safe: field must be a boolean or a list of packages")
+	}
+	for _, name := range packages {
+		if _, ok := env.Packages[name]; !ok {
+			return fmt.Errorf("getting safe: unknown package '%s'", name)
+		}
+	}
+	build.Safe = true
+	build.SafePackages = packages
+	return nil
+}
+
+// IsSafe tells if build runs in safe mode, because -safe option was passed
+// on command line or because root build file requires it
+// Return: a boolean that tells if we are in safe mode
+func (build *Build) IsSafe() bool {
+	if build == nil {
+		return SafeMode
+	}
+	return SafeMode || build.root().Safe
+}
+
+// AllowedPackages returns the list of packages that may be imported in
+// safe mode. Packages listed in root build file can't extend SafePackages
+// when safe mode is set on command line.
+// Return: sorted list of package names
+func (build *Build) AllowedPackages() []string {
+	packages := SafePackages
+	if build != nil && build.root().SafePackages != nil {
+		packages = build.root().SafePackages
+	}
+	var allowed []string
+	for _, name := range packages {
+		if !SafeMode || util.ListContains(SafePackages, name) {
+			allowed = append(allowed, name)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+func (build *Build) root() *Build {
+	if build.Root == nil {
+		return build
+	}
+	return build.Root
+}
+
+// applySafeMode disables unsafe functions in the VM. Imports are checked
+// when scripts are executed, see execute.
+// - vm: the VM to restrict
+// - safe: tells if we are in safe mode
+func applySafeMode(vm *env.Env, safe bool) {
+	if !safe {
+		return
+	}
+	for _, name := range unsafeFunctions {
+		_ = vm.Define(name, unsafeFunction(name))
+	}
+}
+
+// execute runs an Anko script in context VM. In safe mode, script is parsed
+// first and refused if it imports a package that is not allowed, or a package
+// which name is not a literal string.
+// - source: the source of the script
+// Return:
+// - the value of the script
+// - an error if something went wrong
+func (context *Context) execute(source string) (interface{}, error) {
+	if context.safePackages == nil {
+		return vm.Execute(context.VM, nil, source)
+	}
+	statement, err := parser.ParseSrc(source)
+	if err != nil {
+		return nil, err
+	}
+	err = astutil.Walk(statement, func(node interface{}) error {
+		expression, ok := node.(*ast.ImportExpr)
+		if !ok {
+			return nil
+		}
+		literal, ok := expression.Name.(*ast.LiteralExpr)
+		if !ok || literal.Literal.Kind() != reflect.String {
+			return fmt.Errorf("importing packages with computed names is not available in safe mode")
+		}
+		name := literal.Literal.String()
+		if !util.ListContains(context.safePackages, name) {
+			return fmt.Errorf("package '%s' is not available in safe mode", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vm.Run(context.VM, nil, statement)
+}
+
+// unsafeFunction returns a function that fails because given function is
+// not available in safe mode
+func unsafeFunction(name string) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		panic(fmt.Sprintf("function '%s' is not available in safe mode", name))
+	}
+}
@@ -0,0 +1,100 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestParseSafe(t *testing.T) {
+	build := &Build{}
+	if err := ParseSafe(util.Object{"safe": true}, build); err != nil {
+		t.Fatalf("parsing safe: %v", err)
+	}
+	Assert(build.Safe, true, t)
+	Assert(build.AllowedPackages(), SafePackages, t)
+	if err := ParseSafe(util.Object{"safe": []interface{}{"strings", "os"}}, build); err != nil {
+		t.Fatalf("parsing safe: %v", err)
+	}
+	Assert(build.Safe, true, t)
+	Assert(build.AllowedPackages(), []string{"os", "strings"}, t)
+	if err := ParseSafe(util.Object{"safe": []interface{}{"foo"}}, build); err == nil {
+		t.Errorf("unknown package should fail")
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	BuiltinMap["unsafe"] = BuiltinDesc{Name: "unsafe", Func: func() string { return "unsafe" }, Unsafe: true}
+	defer func() {
+		delete(BuiltinMap, "unsafe")
+		SafeMode = false
+	}()
+	// packages listed in build file can't extend safe packages
+	SafeMode = true
+	build := &Build{Safe: true, SafePackages: []string{"strings", "os"}}
+	Assert(build.AllowedPackages(), []string{"strings"}, t)
+	context := NewContext(build)
+	if _, err := context.EvaluateExpression(`strings = import("strings"); strings.ToUpper("foo")`); err != nil {
+		t.Errorf("importing safe package should work: %v", err)
+	}
+	for _, expression := range []string{`import("os")`, `unsafe()`, `load("script.ank")`} {
+		if _, err := context.EvaluateExpression(expression); err == nil {
+			t.Errorf("expression '%s' should fail in safe mode", expression)
+		}
+	}
+	// all packages and builtins are available out of safe mode
+	SafeMode = false
+	context = NewContext(nil)
+	if _, err := context.EvaluateExpression(`import("os")`); err != nil {
+		t.Errorf("importing package should work: %v", err)
+	}
+	value, err := context.EvaluateExpression(`unsafe()`)
+	Assert(err, nil, t)
+	Assert(value, "unsafe", t)
+}
+
+func TestSafeModeIsolation(t *testing.T) {
+	// a context out of safe mode doesn't unlock a safe one and conversely
+	safe := NewContext(&Build{Safe: true})
+	unsafe := NewContext(&Build{})
+	if _, err := unsafe.EvaluateExpression(`import("os")`); err != nil {
+		t.Errorf("importing package should work out of safe mode: %v", err)
+	}
+	for _, expression := range []string{`import("os")`, `name = "os"; import(name)`,
+		`f = func() { return import("os/exec") }; f()`} {
+		if _, err := safe.EvaluateExpression(expression); err == nil {
+			t.Errorf("expression '%s' should fail in safe mode", expression)
+		}
+	}
+	if _, err := safe.Copy().EvaluateExpression(`import("os")`); err == nil {
+		t.Errorf("importing package should fail in copy of safe context")
+	}
+}
+
+func TestSafeModeInit(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	source := "safe: true\nsecrets:\n  TOKEN:\n    command: 'touch " + marker + "'\n"
+	file := filepath.Join(dir, "build.yml")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build, err := NewBuild(file, dir, "", false)
+	if err != nil {
+		t.Fatalf("loading build: %v", err)
+	}
+	// this is what -info -eval does
+	if err := NewContext(build).Init(); err == nil || !strings.Contains(err.Error(), "command secrets are not allowed") {
+		t.Errorf("command secret should fail in safe mode: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("command secret should not run in safe mode")
+	}
+	build = &Build{Safe: true, Scripts: []string{"script.ank"}}
+	if err := NewContext(build).InitScripts(); err == nil {
+		t.Errorf("scripts should fail in safe mode")
+	}
+}
@@ -0,0 +1,250 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+// Scaffold is a structure for project scaffolding of a template
+type Scaffold struct {
+	Variables []Variable
+	Files     []ScaffoldFiles
+}
+
+// Variable is a structure for a variable prompted while scaffolding
+type Variable struct {
+	Name    string
+	Prompt  string
+	Default string
+	Pattern string
+	Error   string
+}
+
+// ScaffoldFiles is a structure for a tree of files rendered while scaffolding
+type ScaffoldFiles struct {
+	From string
+	To   string
+}
+
+// ParseScaffold parses scaffold field of the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseScaffold(object util.Object, build *Build) error {
+	build.Scaffold = nil
+	if !object.HasField("scaffold") {
+		return nil
+	}
+	body, err := object.GetObject("scaffold")
+	if err != nil {
+		return fmt.Errorf("parsing scaffold: %v", err)
+	}
+	if err := body.CheckFields([]string{"variables", "files"}); err != nil {
+		return fmt.Errorf("parsing scaffold: %v", err)
+	}
+	scaffold := &Scaffold{}
+	if body.HasField("variables") {
+		list, err := body.GetList("variables")
+		if err != nil {
+			return fmt.Errorf("parsing scaffold variables: %v", err)
+		}
+		for index, item := range list {
+			variable, err := parseVariable(item)
+			if err != nil {
+				return fmt.Errorf("parsing scaffold variable %d: %v", index+1, err)
+			}
+			scaffold.Variables = append(scaffold.Variables, variable)
+		}
+	}
+	if body.HasField("files") {
+		list, err := body.GetList("files")
+		if err != nil {
+			return fmt.Errorf("parsing scaffold files: %v", err)
+		}
+		for index, item := range list {
+			files, err := parseScaffoldFiles(item)
+			if err != nil {
+				return fmt.Errorf("parsing scaffold files %d: %v", index+1, err)
+			}
+			scaffold.Files = append(scaffold.Files, files)
+		}
+	}
+	build.Scaffold = scaffold
+	return nil
+}
+
+func parseVariable(item interface{}) (Variable, error) {
+	var variable Variable
+	object, err := util.NewObject(item)
+	if err != nil {
+		return variable, err
+	}
+	if err := object.CheckFields([]string{"name", "prompt", "default", "pattern", "error"}); err != nil {
+		return variable, err
+	}
+	if variable.Name, err = object.GetString("name"); err != nil {
+		return variable, err
+	}
+	variable.Prompt = variable.Name
+	fields := map[string]*string{
+		"prompt":  &variable.Prompt,
+		"default": &variable.Default,
+		"pattern": &variable.Pattern,
+		"error":   &variable.Error,
+	}
+	for name, field := range fields {
+		if object.HasField(name) {
+			if *field, err = object.GetString(name); err != nil {
+				return variable, err
+			}
+		}
+	}
+	if variable.Pattern != "" {
+		if _, err := regexp.Compile(variable.Pattern); err != nil {
+			return variable, fmt.Errorf("bad pattern '%s': %v", variable.Pattern, err)
+		}
+	}
+	return variable, nil
+}
+
+func parseScaffoldFiles(item interface{}) (ScaffoldFiles, error) {
+	var files ScaffoldFiles
+	object, err := util.NewObject(item)
+	if err != nil {
+		return files, err
+	}
+	if err := object.CheckFields([]string{"from", "to"}); err != nil {
+		return files, err
+	}
+	if files.From, err = object.GetString("from"); err != nil {
+		return files, err
+	}
+	files.To = "."
+	if object.HasField("to") {
+		if files.To, err = object.GetString("to"); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// Run prompts for variables that are not already defined as properties and
+// renders files of the build directory in current directory:
+// - context: the build context
+// - input: reader for user input (such as os.Stdin)
+// Return: an error if something went wrong
+func (scaffold *Scaffold) Run(context *Context, input io.Reader) error {
+	reader := bufio.NewReader(input)
+	for _, variable := range scaffold.Variables {
+		if _, err := context.GetProperty(variable.Name); err == nil {
+			continue
+		}
+		value, err := variable.Ask(context, reader)
+		if err != nil {
+			return err
+		}
+		context.SetProperty(variable.Name, value)
+	}
+	for _, files := range scaffold.Files {
+		if err := scaffold.render(context, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ask prompts user for value of the variable until it matches pattern:
+// - context: the build context
+// - reader: reader for user input
+// Return:
+// - the value of the variable
+// - an error if something went wrong
+func (variable Variable) Ask(context *Context, reader *bufio.Reader) (string, error) {
+	message := variable.Prompt
+	if variable.Default != "" {
+		message += " [" + variable.Default + "]"
+	}
+	message += ": "
+	for {
+		fmt.Print(message)
+		value, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || value == "") {
+			return "", fmt.Errorf("reading value for '%s': %v", variable.Name, err)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" && variable.Default != "" {
+			value = variable.Default
+		}
+		if variable.Pattern == "" || regexp.MustCompile(variable.Pattern).MatchString(value) {
+			return value, nil
+		}
+		if variable.Error != "" {
+			context.Message(variable.Error)
+		} else {
+			context.MessageArgs("value '%s' doesn't match pattern '%s'", value, variable.Pattern)
+		}
+	}
+}
+
+func (scaffold *Scaffold) render(context *Context, files ScaffoldFiles) error {
+	from := files.From
+	if !filepath.IsAbs(from) {
+		from = filepath.Join(context.Build.Dir, from)
+	}
+	if !util.DirExists(from) {
+		return fmt.Errorf("scaffold directory '%s' not found", files.From)
+	}
+	to, err := context.EvaluateString(files.To)
+	if err != nil {
+		return fmt.Errorf("evaluating destination '%s': %v", files.To, err)
+	}
+	if !filepath.IsAbs(to) {
+		to = filepath.Join(context.Build.Here, to)
+	}
+	names, err := util.FindFiles(from, []string{"**/*"}, nil, false)
+	if err != nil {
+		return fmt.Errorf("listing files in '%s': %v", files.From, err)
+	}
+	for _, name := range names {
+		target, err := context.EvaluateString(name)
+		if err != nil {
+			return fmt.Errorf("evaluating file name '%s': %v", name, err)
+		}
+		target = filepath.Join(to, target)
+		if util.FileExists(target) {
+			return fmt.Errorf("file '%s' already exists", target)
+		}
+		source, err := util.ReadFile(filepath.Join(from, name))
+		if err != nil {
+			return err
+		}
+		content := source
+		if utf8.Valid(source) {
+			text, err := context.EvaluateString(string(source))
+			if err != nil {
+				return fmt.Errorf("rendering file '%s': %v", name, err)
+			}
+			content = []byte(text)
+		}
+		stat, err := os.Stat(filepath.Join(from, name))
+		if err != nil {
+			return fmt.Errorf("stating file '%s': %v", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), util.DirFileMode); err != nil {
+			return fmt.Errorf("creating directory for '%s': %v", target, err)
+		}
+		if err := os.WriteFile(target, content, stat.Mode()); err != nil {
+			return fmt.Errorf("writing file '%s': %v", target, err)
+		}
+		context.MessageArgs("Writing file '%s'", target)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+func TestScaffold(t *testing.T) {
+	base := "/tmp/neon/template"
+	here := "/tmp/neon/project"
+	defer func() {
+		_ = os.RemoveAll("/tmp/neon")
+	}()
+	template := `scaffold:
+  variables:
+  - name:    'name'
+    prompt:  'Name of the project'
+    pattern: '^\w+$'
+  - name:    'version'
+    default: '1.0.0'
+  - name:    'author'
+  files:
+  - from: 'golang'
+    to:   '={name}'`
+	if _, err := WriteFile(base, "golang.tpl", template); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := WriteFile(base+"/golang", "={name}.go", "// ={name} ={version} by ={author}\n"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := os.MkdirAll(here, util.DirFileMode); err != nil {
+		t.Fatalf("making directory: %v", err)
+	}
+	build, err := NewBuild(base+"/golang.tpl", base, "", true)
+	if err != nil {
+		t.Fatalf("loading template: %v", err)
+	}
+	Assert(len(build.Scaffold.Variables), 3, t)
+	Assert(build.Scaffold.Variables[0].Prompt, "Name of the project", t)
+	Assert(build.Scaffold.Variables[2].Prompt, "author", t)
+	if err := build.SetCommandLineProperties("{author: casa}", ""); err != nil {
+		t.Fatalf("setting properties: %v", err)
+	}
+	build.Here = here
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	// first name doesn't match pattern, version takes default value
+	input := strings.NewReader("bad name\ntest\n\n")
+	if err := build.Scaffold.Run(context, input); err != nil {
+		t.Fatalf("running scaffold: %v", err)
+	}
+	content, err := util.ReadFile(here + "/test/test.go")
+	if err != nil {
+		t.Fatalf("reading generated file: %v", err)
+	}
+	Assert(string(content), "// test 1.0.0 by casa\n", t)
+	// running again fails as file already exists
+	context = NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	if err := build.Scaffold.Run(context, strings.NewReader("test\n\n")); err == nil {
+		t.Errorf("scaffold should not overwrite files")
+	}
+}
@@ -0,0 +1,199 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/c4s4/neon/neon/util"
+)
+
+// Secret is a structure for the source of a secret property:
+// - Env: name of the environment variable holding the secret
+// - File: dotenv file holding the secret
+// - Key: name of the variable in dotenv file (defaults to name of the secret)
+// - Command: command that prints the secret
+type Secret struct {
+	Env     string
+	File    string
+	Key     string
+	Command string
+}
+
+// ParseSecrets parses secrets field of the build:
+// - object: the object to parse
+// - build: build that is being constructed
+// Return: an error if something went wrong
+func ParseSecrets(object util.Object, build *Build) error {
+	build.Secrets = make(map[string]Secret)
+	if !object.HasField("secrets") {
+		return nil
+	}
+	secrets, err := object.GetObject("secrets")
+	if err != nil {
+		return fmt.Errorf("parsing secrets: %v", err)
+	}
+	for name, value := range secrets {
+		secret, err := parseSecret(value)
+		if err != nil {
+			return fmt.Errorf("parsing secret '%s': %v", name, err)
+		}
+		build.Secrets[name] = secret
+	}
+	return nil
+}
+
+func parseSecret(value interface{}) (Secret, error) {
+	var secret Secret
+	if env, ok := value.(string); ok {
+		secret.Env = env
+		return secret, nil
+	}
+	object, err := util.NewObject(value)
+	if err != nil {
+		return secret, fmt.Errorf("secret must be a string or a map")
+	}
+	if err := object.CheckFields([]string{"env", "file", "key", "command"}); err != nil {
+		return secret, err
+	}
+	fields := map[string]*string{
+		"env":     &secret.Env,
+		"file":    &secret.File,
+		"key":     &secret.Key,
+		"command": &secret.Command,
+	}
+	for name, field := range fields {
+		if object.HasField(name) {
+			if *field, err = object.GetString(name); err != nil {
+				return secret, err
+			}
+		}
+	}
+	sources := 0
+	for _, source := range []string{secret.Env, secret.File, secret.Command} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return secret, fmt.Errorf("secret must define one of env, file or command")
+	}
+	if secret.Key != "" && secret.File == "" {
+		return secret, fmt.Errorf("key may only be set with file")
+	}
+	return secret, nil
+}
+
+// GetSecrets returns the build secrets, including those inherited from parents
+// Return: secrets by name
+func (build *Build) GetSecrets() map[string]Secret {
+	secrets := make(map[string]Secret)
+	for _, parent := range build.Parents {
+		for name, secret := range parent.GetSecrets() {
+			secrets[name] = secret
+		}
+	}
+	for name, secret := range build.Secrets {
+		secrets[name] = secret
+	}
+	return secrets
+}
+
+// Value returns the value of the secret:
+// - name: the name of the secret
+// - build: the build that defines the secret
+// Return:
+// - the value of the secret
+// - an error if something went wrong
+func (secret Secret) Value(name string, build *Build) (string, error) {
+	if secret.Env != "" {
+		value, ok := os.LookupEnv(secret.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", secret.Env)
+		}
+		return value, nil
+	}
+	if secret.File != "" {
+		env, err := LoadDotEnv(secret.File, build.Dir)
+		if err != nil {
+			return "", err
+		}
+		key := secret.Key
+		if key == "" {
+			key = name
+		}
+		value, ok := env[key]
+		if !ok {
+			return "", fmt.Errorf("variable '%s' not found in dotenv file '%s'", key, secret.File)
+		}
+		return value, nil
+	}
+	shell, err := build.GetShell()
+	if err != nil {
+		return "", err
+	}
+	arguments := append(shell[1:], secret.Command)
+	command := exec.Command(shell[0], arguments...)
+	command.Dir = build.Dir
+	command.Stderr = os.Stderr
+	output, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("running command: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// InitSecrets sets secret properties and registers their values to be masked
+// in output
+// Return: an error if something went wrong
+func (context *Context) InitSecrets() error {
+	secrets := context.Build.GetSecrets()
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if secrets[name].Command != "" && context.Build.IsSafe() {
+			return fmt.Errorf("getting secret '%s': command secrets are not allowed in safe mode", name)
+		}
+		value, err := secrets[name].Value(name, context.Build)
+		if err != nil {
+			return fmt.Errorf("getting secret '%s': %v", name, err)
+		}
+		AddSecret(value)
+		context.SetProperty(name, value)
+	}
+	return nil
+}
+
+// secrets is the list of secret values to mask in output
+var secrets []string
+
+// SecretMask is the text that replaces secret values in output
+const SecretMask = "********"
+
+// AddSecret registers a value to mask in output
+// - value: the secret value
+func AddSecret(value string) {
+	if value == "" || util.ListContains(secrets, value) {
+		return
+	}
+	secrets = append(secrets, value)
+	// longest secrets first so that a secret containing another is masked
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Mask replaces secret values in given text
+// - text: the text to mask
+// Return: the text with secret values replaced with SecretMask
+func Mask(text string) string {
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, SecretMask)
+	}
+	return text
+}
@@ -0,0 +1,67 @@
+package build
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	object := map[string]interface{}{
+		"secrets": map[interface{}]interface{}{
+			"TOKEN":    "DEPLOY_TOKEN",
+			"PASSWORD": map[interface{}]interface{}{"file": ".env", "key": "DB_PASSWORD"},
+			"API_KEY":  map[interface{}]interface{}{"command": "echo key"},
+		},
+	}
+	build := &Build{}
+	if err := ParseSecrets(object, build); err != nil {
+		t.Fatalf("parsing secrets: %v", err)
+	}
+	Assert(build.Secrets["TOKEN"], Secret{Env: "DEPLOY_TOKEN"}, t)
+	Assert(build.Secrets["PASSWORD"], Secret{File: ".env", Key: "DB_PASSWORD"}, t)
+	Assert(build.Secrets["API_KEY"], Secret{Command: "echo key"}, t)
+	object = map[string]interface{}{
+		"secrets": map[interface{}]interface{}{
+			"TOKEN": map[interface{}]interface{}{"env": "FOO", "command": "echo key"},
+		},
+	}
+	if err := ParseSecrets(object, build); err == nil {
+		t.Errorf("secret with two sources should fail")
+	}
+}
+
+func TestSecretValue(t *testing.T) {
+	dir := "/tmp/neon"
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	if _, err := WriteFile(dir, ".env", "DB_PASSWORD=password\n"); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	t.Setenv("NEON_TEST_TOKEN", "token")
+	build := &Build{Dir: dir, Shell: map[string][]string{"default": {"sh", "-c"}}}
+	value, err := Secret{Env: "NEON_TEST_TOKEN"}.Value("TOKEN", build)
+	Assert(err, nil, t)
+	Assert(value, "token", t)
+	value, err = Secret{File: ".env"}.Value("DB_PASSWORD", build)
+	Assert(err, nil, t)
+	Assert(value, "password", t)
+	value, err = Secret{Command: "echo key"}.Value("API_KEY", build)
+	Assert(err, nil, t)
+	Assert(value, "key", t)
+	if _, err = (Secret{Env: "NEON_TEST_UNDEFINED"}).Value("FOO", build); err == nil {
+		t.Errorf("undefined environment variable should fail")
+	}
+}
+
+func TestMask(t *testing.T) {
+	defer func() {
+		secrets = nil
+	}()
+	AddSecret("secret")
+	AddSecret("topsecret")
+	AddSecret("")
+	Assert(Mask("token is topsecret, password is secret"),
+		"token is ********, password is ********", t)
+	Assert(Mask("nothing to hide"), "nothing to hide", t)
+}
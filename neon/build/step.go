@@ -48,7 +48,7 @@ func NewScriptStep(script string) (Step, error) {
 // - context: the build context to run tha script
 // Return: an error if something went wrong
 func (step ScriptStep) Run(context *Context) error {
-	_, err := context.EvaluateExpression(step.Script)
+	_, err := context.ExecuteScript(step.Script)
 	if err != nil {
 		return fmt.Errorf("evaluating script: %v", err)
 	}
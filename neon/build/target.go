@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/c4s4/neon/neon/util"
 )
 
+// TargetScopes is the list of possible scopes for targets
+var TargetScopes = []string{"shared", "isolated"}
+
 // Target is a structure for a target
 type Target struct {
 	Build   *Build
@@ -15,6 +19,8 @@ type Target struct {
 	Doc     string
 	Depends []string
 	Unless  string
+	Scope   string
+	Export  []string
 	Steps   Steps
 }
 
@@ -30,7 +36,7 @@ func NewTarget(build *Build, name string, object util.Object) (*Target, error) {
 		Build: build,
 		Name:  name,
 	}
-	if err := object.CheckFields([]string{"doc", "depends", "unless", "steps"}); err != nil {
+	if err := object.CheckFields([]string{"doc", "depends", "unless", "scope", "export", "steps"}); err != nil {
 		return nil, err
 	}
 	if err := ParseTargetDoc(object, target); err != nil {
@@ -42,6 +48,9 @@ func NewTarget(build *Build, name string, object util.Object) (*Target, error) {
 	if err := ParseTargetUnless(object, target); err != nil {
 		return nil, err
 	}
+	if err := ParseTargetScope(object, target); err != nil {
+		return nil, err
+	}
 	if err := ParseTargetSteps(object, target); err != nil {
 		return nil, err
 	}
@@ -93,6 +102,35 @@ func ParseTargetUnless(object util.Object, target *Target) error {
 	return nil
 }
 
+// ParseTargetScope parses scope and exported properties of the target:
+// - object: body of the target as an interface
+// - target: the target being parsed
+// Return: an error if something went wrong
+func ParseTargetScope(object util.Object, target *Target) error {
+	if object.HasField("scope") {
+		scope, err := object.GetString("scope")
+		if err != nil {
+			return fmt.Errorf("scope field in target '%s' must be a string", target.Name)
+		}
+		if !util.ListContains(TargetScopes, scope) {
+			return fmt.Errorf("scope of target '%s' must be one of %s", target.Name,
+				strings.Join(TargetScopes, ", "))
+		}
+		target.Scope = scope
+	}
+	if object.HasField("export") {
+		export, err := object.GetListStringsOrString("export")
+		if err != nil {
+			return fmt.Errorf("export field in target '%s' must be a string or list of strings", target.Name)
+		}
+		if target.Scope != "isolated" {
+			return fmt.Errorf("export field in target '%s' requires isolated scope", target.Name)
+		}
+		target.Export = export
+	}
+	return nil
+}
+
 // ParseTargetSteps parses steps of a target:
 // - object: the target body as an interface
 // - target: the target being parsed
@@ -160,9 +198,48 @@ func (target *Target) Run(context *Context) error {
 			return fmt.Errorf("changing to build directory '%s'", target.Build.Dir)
 		}
 	}
-	run_err := target.Steps.Run(context)
+	var run_err error
+	if target.Scope == "isolated" {
+		run_err = target.runIsolated(context)
+	} else {
+		run_err = target.Steps.Run(context)
+	}
+	if err := context.RunFinalizers(target); err != nil && run_err == nil {
+		run_err = err
+	}
 	if err := context.Stack.Pop(); err != nil {
 		return err
 	}
 	return run_err
 }
+
+// runIsolated runs steps of the target in a copy of the VM, so that
+// properties it sets don't leak in other targets, except exported ones:
+// - context: the context of the build
+// Return: an error if something went wrong
+func (target *Target) runIsolated(context *Context) error {
+	isolated := &Context{
+		VM:           context.VM.DeepCopy(),
+		Evaluator:    context.Evaluator,
+		Build:        context.Build,
+		Stack:        context.Stack,
+		History:      context.History,
+		finalizers:   context.finalizers,
+		safePackages: context.safePackages,
+	}
+	if context.lazy != nil {
+		isolated.lazy = context.lazy.copy(isolated)
+		isolated.VM.SetExternalLookup(isolated.lazy)
+	}
+	if err := target.Steps.Run(isolated); err != nil {
+		return err
+	}
+	for _, name := range target.Export {
+		value, err := isolated.GetProperty(name)
+		if err != nil {
+			return fmt.Errorf("exporting property '%s' of target '%s': %v", name, target.Name, err)
+		}
+		context.SetProperty(name, value)
+	}
+	return nil
+}
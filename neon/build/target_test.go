@@ -61,3 +61,99 @@ func TestTarget(t *testing.T) {
 		t.Errorf("Bad value: %v", value)
 	}
 }
+
+func TestTargetIsolated(t *testing.T) {
+	build := &Build{Properties: map[string]interface{}{"global": "build"}}
+	build.SetDir(".")
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	object := map[string]interface{}{
+		"scope":  "isolated",
+		"export": "exported",
+		"steps":  []interface{}{`global = "target"; local = "target"; exported = "target"`},
+	}
+	target, err := NewTarget(build, "test", object)
+	if err != nil {
+		t.Fatalf("parsing target: %v", err)
+	}
+	if err := target.Run(context); err != nil {
+		t.Fatalf("running target: %v", err)
+	}
+	value, _ := context.GetProperty("global")
+	Assert(value, "build", t)
+	if _, err := context.GetProperty("local"); err == nil {
+		t.Errorf("local property should not leak out of isolated target")
+	}
+	value, _ = context.GetProperty("exported")
+	Assert(value, "target", t)
+	// export requires isolated scope
+	object = map[string]interface{}{
+		"export": "exported",
+	}
+	if _, err := NewTarget(build, "test", object); err == nil {
+		t.Errorf("export without isolated scope should fail")
+	}
+	object = map[string]interface{}{
+		"scope": "private",
+	}
+	if _, err := NewTarget(build, "test", object); err == nil {
+		t.Errorf("unknown scope should fail")
+	}
+}
+
+func TestTargetIsolatedSafe(t *testing.T) {
+	build := &Build{Safe: true}
+	build.SetDir(".")
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	object := map[string]interface{}{
+		"scope": "isolated",
+		"steps": []interface{}{`os = import("os")`},
+	}
+	target, err := NewTarget(build, "test", object)
+	if err != nil {
+		t.Fatalf("parsing target: %v", err)
+	}
+	if err := target.Run(context); err == nil {
+		t.Errorf("importing package should fail in isolated target in safe mode")
+	}
+}
+
+func TestTargetFinalizers(t *testing.T) {
+	build := &Build{}
+	build.SetDir(".")
+	context := NewContext(build)
+	if err := context.Init(); err != nil {
+		t.Fatalf("initializing context: %v", err)
+	}
+	var calls []string
+	BuiltinMap = make(map[string]BuiltinDesc)
+	AddBuiltin(BuiltinDesc{
+		Name: "finalize",
+		Func: func(build bool, name string) {
+			context.AddFinalizer(build, func() error {
+				calls = append(calls, name)
+				return nil
+			})
+		},
+	})
+	LoadBuiltins(context.VM, false)
+	object := map[string]interface{}{
+		"steps": []interface{}{`finalize(false, "first")`, `finalize(false, "second")`,
+			`finalize(true, "build")`, `undefined()`},
+	}
+	target, err := NewTarget(build, "test", object)
+	if err != nil {
+		t.Fatalf("parsing target: %v", err)
+	}
+	if err := target.Run(context); err == nil {
+		t.Errorf("target should fail")
+	}
+	Assert(calls, []string{"second", "first"}, t)
+	Assert(context.RunFinalizers(nil), nil, t)
+	Assert(calls, []string{"second", "first", "build"}, t)
+}
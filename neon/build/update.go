@@ -61,7 +61,7 @@ func printNewRelease() {
 }
 
 func updateRepository(repository string, batch bool) error {
-	plugins, err := util.FindFiles(repository, []string{"*/*"}, nil, true)
+	plugins, err := FindPlugins(repository)
 	if err != nil {
 		return fmt.Errorf("searching plugins: %v", err)
 	}
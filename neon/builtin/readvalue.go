@@ -0,0 +1,57 @@
+package builtin
+
+import (
+	"os"
+
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+)
+
+func init() {
+	build.AddBuiltin(build.BuiltinDesc{
+		Name: "readvalue",
+		Func: readValue,
+		Help: `Read value at given path in a YAML, JSON or TOML file.
+
+Arguments:
+
+- The file name to read, format is guessed from extension ('.yml', '.yaml',
+  '.json' or '.toml').
+- The path of the value, with keys separated with dots and indexes in brackets
+  (such as 'a.b[2].c'). Keys with dots are quoted in brackets (such as
+  'a["b.c"]').
+
+Returns:
+
+- The value at given path.
+
+Examples:
+
+    # read version in package.json
+    readvalue("package.json", "version")
+    # returns: the version, such as "1.2.3"
+    # read name of first dependency in Chart.yaml
+    readvalue("Chart.yaml", "dependencies[0].name")
+    # returns: the name of first dependency
+
+Notes:
+
+- Indexes are not supported in TOML files.`,
+	})
+}
+
+func readValue(file, path string) interface{} {
+	format, err := util.DataFormat(file, "")
+	if err != nil {
+		panic(err.Error())
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		panic(err.Error())
+	}
+	value, err := util.GetDataPath(content, format, path)
+	if err != nil {
+		panic(err.Error())
+	}
+	return value
+}
@@ -0,0 +1,17 @@
+package builtin
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadValue(t *testing.T) {
+	file := "/tmp/test.json"
+	_ = os.WriteFile(file, []byte(`{"name": "app", "tags": ["foo", "bar"]}`), 0644)
+	defer func() {
+		_ = os.Remove(file)
+	}()
+	if actual := readValue(file, "tags[1]"); actual != "bar" {
+		t.Fatalf("bad value: expected bar, got %v", actual)
+	}
+}
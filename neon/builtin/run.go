@@ -8,8 +8,9 @@ import (
 
 func init() {
 	build.AddBuiltin(build.BuiltinDesc{
-		Name: "run",
-		Func: run,
+		Name:   "run",
+		Unsafe: true,
+		Func:   run,
 		Help: `Run given command and return output.
 
 Arguments:
@@ -26,7 +27,11 @@ Examples:
 
     # zip files of foo directory in bar.zip file
     run("zip", "-r", "bar.zip", "foo")
-    # returns: the trimed output of the command`,
+    # returns: the trimed output of the command
+
+Notes:
+
+- This builtin is not available in safe mode.`,
 	})
 }
 
@@ -7,8 +7,9 @@ import (
 
 func init() {
 	build.AddBuiltin(build.BuiltinDesc{
-		Name: "setenv",
-		Func: setenv,
+		Name:   "setenv",
+		Unsafe: true,
+		Func:   setenv,
 		Help: `Set environment variable.
 
 Arguments:
@@ -19,7 +20,11 @@ Arguments:
 Examples:
 
     # set foo to value bar
-    setenv("foo", "bar")`})
+    setenv("foo", "bar")
+
+Notes:
+
+- This builtin is not available in safe mode.`})
 }
 
 func setenv(name, value string) error {
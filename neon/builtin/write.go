@@ -8,8 +8,9 @@ import (
 
 func init() {
 	build.AddBuiltin(build.BuiltinDesc{
-		Name: "write",
-		Func: write,
+		Name:   "write",
+		Unsafe: true,
+		Func:   write,
 		Help: `Write a string in given file.
 
 Arguments:
@@ -20,7 +21,11 @@ Arguments:
 Examples:
 
     # write "1.2.3" in VERSION file
-    write("VERSION", "1.2.3")`,
+    write("VERSION", "1.2.3")
+
+Notes:
+
+- This builtin is not available in safe mode.`,
 	})
 }
 
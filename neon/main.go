@@ -34,6 +34,12 @@ type Configuration struct {
 	Time bool
 	// Repo location
 	Repo string
+	// Site is the default site to install plugins from
+	Site string
+	// KeyFile is the file holding the key for encrypted configuration files
+	KeyFile string
+	// PropsPrefix is the prefix of environment variables that define properties
+	PropsPrefix string
 	// Links associates build files to directories
 	Links map[string]string
 }
@@ -82,6 +88,14 @@ func LoadConfiguration(file string) (*Configuration, error) {
 		}
 		_build.ApplyTheme(theme)
 	}
+	// apply key file
+	if configuration.KeyFile != "" {
+		_build.KeyFile = configuration.KeyFile
+	}
+	// apply properties prefix
+	if configuration.PropsPrefix != "" {
+		_build.PropertyPrefix = configuration.PropsPrefix
+	}
 	// expand user homes in files
 	abs := make(map[string]string)
 	for dir, build := range configuration.Links {
@@ -97,6 +111,7 @@ type Options struct {
 	Info         bool
 	Version      bool
 	Props        string
+	PropsFile    string
 	Time         bool
 	Tasks        bool
 	Task         string
@@ -114,6 +129,16 @@ type Options struct {
 	Template     string
 	Templates    bool
 	Parents      bool
+	Plugins      bool
+	Plugin       string
+	Remove       string
+	Outdated     bool
+	Lock         bool
+	Encrypt      string
+	Decrypt      string
+	Safe         bool
+	Eval         bool
+	Why          string
 	Theme        string
 	Themes       bool
 	Targets      []string
@@ -125,6 +150,7 @@ func ParseCommandLine() *Options {
 	info := flag.Bool("info", false, "Print build information")
 	version := flag.Bool("version", false, "Print neon version")
 	props := flag.String("props", "", "Build properties")
+	propsFile := flag.String("props-file", "", "YAML or JSON file with build properties")
 	timeit := flag.Bool("time", false, "Print build duration")
 	tasks := flag.Bool("tasks", false, "Print tasks list")
 	task := flag.String("task", "", "Print help on given task")
@@ -142,6 +168,16 @@ func ParseCommandLine() *Options {
 	template := flag.String("template", "", "Run given template")
 	templates := flag.Bool("templates", false, "List available templates in repository")
 	parents := flag.Bool("parents", false, "List available parent build files in repository")
+	plugins := flag.Bool("plugins", false, "List installed plugins with their revision")
+	plugin := flag.String("plugin", "", "Print parents, templates and scripts of given plugin")
+	remove := flag.String("remove", "", "Remove given plugin from repository")
+	outdated := flag.Bool("outdated", false, "List plugins that are behind their remote")
+	lock := flag.Bool("lock", false, "Write lock file with checksums of repository files")
+	encrypt := flag.String("encrypt", "", "Encrypt given configuration file in place")
+	decrypt := flag.String("decrypt", "", "Decrypt given configuration file in place")
+	eval := flag.Bool("eval", false, "Evaluate properties in build information")
+	why := flag.String("why", "", "Print definitions of given property")
+	safe := flag.Bool("safe", false, "Disable unsafe packages and builtins for untrusted build files")
 	theme := flag.String("theme", "", "Apply given color theme")
 	themes := flag.Bool("themes", false, "Print all available color themes")
 	flag.Parse()
@@ -151,6 +187,7 @@ func ParseCommandLine() *Options {
 		Info:         *info,
 		Version:      *version,
 		Props:        *props,
+		PropsFile:    *propsFile,
 		Time:         *timeit,
 		Tasks:        *tasks,
 		Task:         *task,
@@ -168,6 +205,16 @@ func ParseCommandLine() *Options {
 		Template:     *template,
 		Templates:    *templates,
 		Parents:      *parents,
+		Plugins:      *plugins,
+		Plugin:       *plugin,
+		Remove:       *remove,
+		Outdated:     *outdated,
+		Lock:         *lock,
+		Encrypt:      *encrypt,
+		Decrypt:      *decrypt,
+		Safe:         *safe,
+		Eval:         *eval,
+		Why:          *why,
 		Theme:        *theme,
 		Themes:       *themes,
 		Targets:      targets,
@@ -242,6 +289,7 @@ func run() error {
 		repo = util.ExpandUserHome(repo)
 	}
 	_build.Gray = opts.Grey
+	_build.SafeMode = opts.Safe
 	configuration.Time = opts.Time
 	if printInfo(opts, repo) {
 		return nil
@@ -250,8 +298,37 @@ func run() error {
 		_build.Message(_build.NeonVersion)
 		return nil
 	} else if opts.Install != "" {
-		err := _build.InstallPlugin(opts.Install, repo)
+		err := _build.InstallPlugin(opts.Install, repo, configuration.Site)
 		return err
+	} else if opts.Encrypt != "" {
+		if err := _build.EncryptFile(opts.Encrypt); err != nil {
+			return err
+		}
+		_build.MessageArgs("File '%s' encrypted", opts.Encrypt)
+		return nil
+	} else if opts.Decrypt != "" {
+		if err := _build.DecryptFile(opts.Decrypt); err != nil {
+			return err
+		}
+		_build.MessageArgs("File '%s' decrypted", opts.Decrypt)
+		return nil
+	} else if opts.Remove != "" {
+		err := _build.RemovePlugin(opts.Remove, repo)
+		return err
+	} else if opts.Plugin != "" {
+		text, err := _build.InfoPlugin(opts.Plugin, repo)
+		if err != nil {
+			return err
+		}
+		_build.Message(text)
+		return nil
+	} else if opts.Outdated {
+		text, err := _build.InfoOutdated(repo)
+		if err != nil {
+			return err
+		}
+		_build.Message(text)
+		return nil
 	} else if opts.Theme != "" {
 		err := _build.ApplyThemeByName(opts.Theme)
 		if err != nil {
@@ -268,6 +345,12 @@ func run() error {
 		if err != nil {
 			return err
 		}
+		if err = verifyTemplate(opts.Template, file, repo); err != nil {
+			return err
+		}
+	}
+	if opts.Lock {
+		_build.CheckIntegrity = false
 	}
 	path, base, err := FindBuildFile(file, repo, configuration)
 	if err != nil {
@@ -277,25 +360,41 @@ func run() error {
 	if err != nil {
 		return err
 	}
-	err = build.SetCommandLineProperties(opts.Props)
+	err = build.SetCommandLineProperties(opts.Props, opts.PropsFile)
 	if err != nil {
 		return err
 	}
-	if opts.PrintTargets {
-		_build.Message(build.FormatTargets())
-	} else if opts.Info {
-		context := _build.NewContext(build)
-		err = context.Init()
+	if opts.Lock {
+		path, err := build.WriteLockFile()
 		if err != nil {
 			return err
 		}
-		text, err := build.Info(context)
+		_build.MessageArgs("Lock file written in '%s'", path)
+	} else if opts.PrintTargets {
+		_build.Message(build.FormatTargets())
+	} else if opts.Info || opts.Why != "" {
+		var context *_build.Context
+		if opts.Eval {
+			context = _build.NewContext(build)
+			err = context.Init()
+			if err != nil {
+				return err
+			}
+		}
+		var text string
+		if opts.Why != "" {
+			text, err = build.InfoOrigins(opts.Why, context)
+		} else {
+			text, err = build.Info(context)
+		}
 		if err != nil {
 			return err
 		}
 		_build.Message(text)
 	} else if opts.Tree {
 		build.Tree()
+	} else if opts.Safe {
+		return fmt.Errorf("running targets is not allowed in safe mode")
 	} else {
 		err = os.Chdir(build.Dir)
 		if err != nil {
@@ -306,7 +405,15 @@ func run() error {
 		if err != nil {
 			return err
 		}
-		err = build.Run(context, opts.Targets)
+		scaffold := scaffolding(opts, build)
+		if scaffold {
+			if err = build.Scaffold.Run(context, os.Stdin); err != nil {
+				return err
+			}
+		}
+		if !scaffold || len(build.GetTargets()) > 0 || len(opts.Targets) > 0 {
+			err = build.Run(context, opts.Targets)
+		}
 		duration := time.Since(start)
 		if configuration.Time || duration.Seconds() > 10 {
 			_build.InfoArgs("Build duration: %s", duration.String())
@@ -319,6 +426,29 @@ func run() error {
 	return nil
 }
 
+// verifyTemplate verifies template checksum with lock file in current
+// directory, if any
+func verifyTemplate(name, file, repo string) error {
+	here, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %v", err)
+	}
+	checksums, err := _build.LoadLockFile(here)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %v", err)
+	}
+	if err := _build.VerifyChecksum(name, file, checksums, repo); err != nil {
+		return fmt.Errorf("verifying template '%s': %v", name, err)
+	}
+	return nil
+}
+
+// scaffolding tells if build scaffolding should run, which is only the case
+// when running a template
+func scaffolding(opts *Options, build *_build.Build) bool {
+	return opts.Template != "" && build.Scaffold != nil
+}
+
 // printInfo prints build information if requested
 func printInfo(opts *Options, repo string) bool {
 	if opts.Tasks {
@@ -339,6 +469,9 @@ func printInfo(opts *Options, repo string) bool {
 	} else if opts.Parents {
 		_build.Message(_build.InfoParents(repo))
 		return true
+	} else if opts.Plugins {
+		_build.Message(_build.InfoPlugins(repo))
+		return true
 	} else if opts.Themes {
 		_build.Message(_build.InfoThemes())
 		return true
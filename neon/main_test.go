@@ -89,14 +89,17 @@ foo: bars`
 
 func TestParseCommandLine(t *testing.T) {
 	os.Args = []string{"cmd", "-file", "file", "-info", "-version", "-props", "{foo: bar, spam: eggs}",
-		"-time", "-tasks", "-task", "task", "-targets", "-builtins", "-builtin", "builtin", "-tree",
+		"-props-file", "props.yml", "-time", "-tasks", "-task", "task", "-targets", "-builtins", "-builtin", "builtin", "-tree",
 		"-tasks-ref", "-builtins-ref", "-install", "install", "-repo", "repo", "-update", "-batch", "-grey",
-		"-template", "template", "-templates", "-themes", "-theme", "test", "-parents", "target1", "target2"}
+		"-template", "template", "-templates", "-themes", "-theme", "test", "-parents", "-plugins",
+		"-plugin", "plugin", "-remove", "remove", "-outdated", "-lock", "-encrypt", "encrypt",
+		"-decrypt", "decrypt", "-safe", "-eval", "-why", "VERSION", "target1", "target2"}
 	opts := ParseCommandLine()
 	Assert(opts.File, "file", t)
 	Assert(opts.Info, true, t)
 	Assert(opts.Version, true, t)
 	Assert(opts.Props, "{foo: bar, spam: eggs}", t)
+	Assert(opts.PropsFile, "props.yml", t)
 	Assert(opts.Time, true, t)
 	Assert(opts.Tasks, true, t)
 	Assert(opts.Task, "task", t)
@@ -114,6 +117,16 @@ func TestParseCommandLine(t *testing.T) {
 	Assert(opts.Template, "template", t)
 	Assert(opts.Templates, true, t)
 	Assert(opts.Parents, true, t)
+	Assert(opts.Plugins, true, t)
+	Assert(opts.Plugin, "plugin", t)
+	Assert(opts.Remove, "remove", t)
+	Assert(opts.Outdated, true, t)
+	Assert(opts.Lock, true, t)
+	Assert(opts.Encrypt, "encrypt", t)
+	Assert(opts.Decrypt, "decrypt", t)
+	Assert(opts.Safe, true, t)
+	Assert(opts.Eval, true, t)
+	Assert(opts.Why, "VERSION", t)
 	Assert(opts.Theme, "test", t)
 	Assert(opts.Themes, true, t)
 	Assert(opts.Targets, []string{"target1", "target2"}, t)
@@ -149,3 +162,10 @@ func Assert(actual, expected interface{}, t *testing.T) {
 		t.Errorf("actual (\"%s\") != expected (\"%s\")", actual, expected)
 	}
 }
+
+func TestScaffolding(t *testing.T) {
+	scaffolded := &build.Build{Scaffold: &build.Scaffold{}}
+	Assert(scaffolding(&Options{Template: "c4s4/build/golang.tpl"}, scaffolded), true, t)
+	Assert(scaffolding(&Options{}, scaffolded), false, t)
+	Assert(scaffolding(&Options{Template: "c4s4/build/golang.tpl"}, &build.Build{}), false, t)
+}
@@ -0,0 +1,244 @@
+package task
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	t "time"
+
+	zglob "github.com/mattn/go-zglob"
+)
+
+// TarFormats lists supported tar formats with their file extensions
+var TarFormats = map[string][]string{
+	"tar":     {".tar"},
+	"tar.gz":  {".tar.gz", ".tgz"},
+	"tar.bz2": {".tar.bz2", ".tbz2"},
+	"tar.xz":  {".tar.xz", ".txz"},
+	"tar.zst": {".tar.zst", ".tzst"},
+}
+
+// compressors are external commands used for formats without compressor
+// in standard library (bz2 can be read but not written by standard library)
+var compressors = map[string][]string{
+	"tar.bz2": {"bzip2", "-c"},
+	"tar.xz":  {"xz", "-c", "-T1"},
+	"tar.zst": {"zstd", "-c", "-q"},
+}
+
+// decompressors are external commands used to read formats without
+// decompressor in standard library
+var decompressors = map[string][]string{
+	"tar.xz":  {"xz", "-d", "-c"},
+	"tar.zst": {"zstd", "-d", "-c", "-q"},
+}
+
+// ReproducibleTime is modification time of files in reproducible archives if
+// SOURCE_DATE_EPOCH environment variable is not set (earliest zip date)
+var ReproducibleTime = t.Date(1980, 1, 1, 0, 0, 0, 0, t.UTC)
+
+// tarFormat returns the format of a tar archive:
+// - file: the name of the archive
+// - format: format set by user, guessed from file name if empty
+// Return: format of the archive and an error if format is unknown
+func tarFormat(file, format string) (string, error) {
+	if format != "" {
+		if _, ok := TarFormats[format]; !ok {
+			return "", fmt.Errorf("unknown tar format '%s'", format)
+		}
+		return format, nil
+	}
+	for name, extensions := range TarFormats {
+		for _, extension := range extensions {
+			if strings.HasSuffix(file, extension) && name != "tar" {
+				return name, nil
+			}
+		}
+	}
+	// for compatibility, file names ending with gz are gzip compressed
+	if strings.HasSuffix(file, "gz") {
+		return "tar.gz", nil
+	}
+	return "tar", nil
+}
+
+// compressWriter returns a writer that compresses in given format:
+// - writer: the writer to compress to
+// - format: the tar format
+// Return: the writer to compress and an error if something went wrong
+func compressWriter(writer io.Writer, format string) (io.WriteCloser, error) {
+	if format == "tar.gz" {
+		return gzip.NewWriter(writer), nil
+	}
+	if command, ok := compressors[format]; ok {
+		return newCommandWriter(writer, command)
+	}
+	return nopWriteCloser{Writer: writer}, nil
+}
+
+// decompressReader returns a reader that decompresses given format:
+// - reader: the reader to decompress
+// - format: the tar format
+// Return: the reader of uncompressed data and an error if something went wrong
+func decompressReader(reader io.Reader, format string) (io.ReadCloser, error) {
+	switch format {
+	case "tar.gz":
+		return gzip.NewReader(reader)
+	case "tar.bz2":
+		return io.NopCloser(bzip2.NewReader(reader)), nil
+	}
+	if command, ok := decompressors[format]; ok {
+		return newCommandReader(reader, command)
+	}
+	return io.NopCloser(reader), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (writer nopWriteCloser) Close() error {
+	return nil
+}
+
+// commandWriter pipes written data through an external command
+type commandWriter struct {
+	pipe    io.WriteCloser
+	command *exec.Cmd
+}
+
+func newCommandWriter(writer io.Writer, command []string) (*commandWriter, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = writer
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("running '%s' (must be in PATH for this format): %v", command[0], err)
+	}
+	return &commandWriter{pipe: pipe, command: cmd}, nil
+}
+
+func (writer *commandWriter) Write(data []byte) (int, error) {
+	return writer.pipe.Write(data)
+}
+
+func (writer *commandWriter) Close() error {
+	if err := writer.pipe.Close(); err != nil {
+		return err
+	}
+	if err := writer.command.Wait(); err != nil {
+		return fmt.Errorf("compressing with '%s': %v", writer.command.Args[0], err)
+	}
+	return nil
+}
+
+// commandReader reads data piped through an external command
+type commandReader struct {
+	pipe    io.ReadCloser
+	command *exec.Cmd
+}
+
+func newCommandReader(reader io.Reader, command []string) (*commandReader, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = reader
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("running '%s' (must be in PATH for this format): %v", command[0], err)
+	}
+	return &commandReader{pipe: pipe, command: cmd}, nil
+}
+
+func (reader *commandReader) Read(data []byte) (int, error) {
+	return reader.pipe.Read(data)
+}
+
+func (reader *commandReader) Close() error {
+	// drain output so that command may terminate
+	_, _ = io.Copy(io.Discard, reader.pipe)
+	if err := reader.command.Wait(); err != nil {
+		return fmt.Errorf("decompressing with '%s': %v", reader.command.Args[0], err)
+	}
+	return nil
+}
+
+// reproducibleTime returns modification time for files in reproducible
+// archives, taken from SOURCE_DATE_EPOCH environment variable if set
+// Return: modification time and an error if variable is not a timestamp
+func reproducibleTime() (t.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return ReproducibleTime, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return t.Time{}, fmt.Errorf("parsing SOURCE_DATE_EPOCH: %v", err)
+	}
+	return t.Unix(seconds, 0).UTC(), nil
+}
+
+// entryPath returns the path where to expand an archive entry:
+// - name: the name of the entry in the archive
+// - dir: the destination directory
+// - strip: number of leading path elements to remove from name
+// - includes: globs of names to expand, all if empty
+// Return: destination path, empty if entry is not expanded, and an error
+func entryPath(name, dir string, strip int, includes []string) (string, error) {
+	name = SanitizeName(name)
+	if len(includes) > 0 {
+		included := false
+		for _, include := range includes {
+			match, err := zglob.Match(include, name)
+			if err != nil {
+				return "", fmt.Errorf("matching entry '%s': %v", name, err)
+			}
+			if match {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return "", nil
+		}
+	}
+	if strip > 0 {
+		parts := strings.Split(strings.Trim(name, "/"), "/")
+		if len(parts) <= strip {
+			return "", nil
+		}
+		name = strings.Join(parts[strip:], "/")
+	}
+	if name == "" {
+		return "", nil
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// checkLink checks that a symbolic link doesn't point outside a directory:
+// - path: the path of the link
+// - target: the target of the link
+// - dir: the directory that must contain target
+// Return: an error if target is outside of directory
+func checkLink(path, target, dir string) error {
+	if filepath.IsAbs(target) {
+		return fmt.Errorf("link '%s' has absolute target '%s'", path, target)
+	}
+	resolved := filepath.Join(filepath.Dir(path), target)
+	relative, err := filepath.Rel(dir, resolved)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("link '%s' points outside of destination directory", path)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package task
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChecksumAlgorithms maps checksum algorithms to hash constructors
+var ChecksumAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "checksum",
+		Func: checksum,
+		Args: reflect.TypeOf(checksumArgs{}),
+		Help: `Write or verify checksums of files.
+
+Arguments:
+
+- checksum: globs of files to compute checksums for (strings, file, wrap).
+- dir: root directory for globs, defaults to '.' (string, optional, file).
+- exclude: globs of files to exclude (strings, optional, file, wrap).
+- algorithm: checksum algorithm, 'sha256' or 'sha512', defaults to 'sha256'
+  (string, optional).
+- tofile: sums file to write (string, optional, file).
+- verify: sums file to verify files against (string, optional, file).
+
+Examples:
+
+    # write SHA-256 sums of archives in build directory
+    - checksum: '*.tar.gz'
+      dir:      'build'
+      tofile:   'build/SHA256SUMS'
+    # verify archives in build directory
+    - checksum: '*.tar.gz'
+      dir:      'build'
+      verify:   'build/SHA256SUMS'
+
+Notes:
+
+- Sums files have the format of sha256sum and sha512sum commands, with file
+  names relative to dir, so that they can be checked with 'sha256sum -c'.
+- One and only one of parameters 'tofile' and 'verify' must be set.
+- When verifying, algorithm is guessed from length of sums in sums file, all
+  selected files must be listed in sums file with the right checksum and all
+  files listed in sums file must exist.`,
+	})
+}
+
+type checksumArgs struct {
+	Checksum  []string `neon:"file,wrap"`
+	Dir       string   `neon:"optional,file"`
+	Exclude   []string `neon:"optional,file,wrap"`
+	Algorithm string   `neon:"optional"`
+	Tofile    string   `neon:"optional,file"`
+	Verify    string   `neon:"optional,file"`
+}
+
+func checksum(context *build.Context, args interface{}) error {
+	params := args.(checksumArgs)
+	if (params.Tofile == "") == (params.Verify == "") {
+		return fmt.Errorf("one and only one of parameters 'tofile' and 'verify' must be set")
+	}
+	files, err := util.FindFiles(params.Dir, params.Checksum, params.Exclude, false)
+	if err != nil {
+		return fmt.Errorf("getting source files for checksum task: %v", err)
+	}
+	if params.Verify != "" {
+		context.MessageArgs("Verifying checksums of %d file(s) with '%s'", len(files), params.Verify)
+		return verifyChecksums(params.Dir, files, params.Verify)
+	}
+	algorithm := params.Algorithm
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, ok := ChecksumAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unknown checksum algorithm '%s'", algorithm)
+	}
+	context.MessageArgs("Writing %s checksums of %d file(s) in '%s'", algorithm, len(files), params.Tofile)
+	var builder strings.Builder
+	for _, file := range files {
+		sum, err := fileChecksum(filepath.Join(params.Dir, file), newHash)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(sum + "  " + filepath.ToSlash(file) + "\n")
+	}
+	if err := os.WriteFile(params.Tofile, []byte(builder.String()), FileMode); err != nil {
+		return fmt.Errorf("writing sums file: %v", err)
+	}
+	return nil
+}
+
+func verifyChecksums(dir string, files []string, sumsFile string) error {
+	sums, err := readChecksums(sumsFile)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	selected := make(map[string]bool)
+	for _, file := range files {
+		name := filepath.ToSlash(file)
+		selected[name] = true
+		expected, ok := sums[name]
+		if !ok {
+			failed = append(failed, name+" (not listed)")
+			continue
+		}
+		newHash := sha256.New
+		if len(expected) == 2*sha512.Size {
+			newHash = sha512.New
+		}
+		sum, err := fileChecksum(filepath.Join(dir, file), newHash)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			failed = append(failed, name)
+		}
+	}
+	// files listed in sums file must exist, as with sha256sum -c
+	var missing []string
+	for name := range sums {
+		if !selected[name] && !util.FileExists(filepath.Join(dir, filepath.FromSlash(name))) {
+			missing = append(missing, name+" (missing)")
+		}
+	}
+	sort.Strings(missing)
+	failed = append(failed, missing...)
+	if len(failed) > 0 {
+		return fmt.Errorf("checksum verification failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func readChecksums(file string) (map[string]string, error) {
+	stream, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("opening sums file: %v", err)
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(stream)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		index := strings.Index(line, " ")
+		if index < 0 {
+			return nil, fmt.Errorf("bad line %d in sums file '%s'", number, file)
+		}
+		sum := strings.ToLower(line[:index])
+		if len(sum) != 2*sha256.Size && len(sum) != 2*sha512.Size {
+			return nil, fmt.Errorf("bad checksum on line %d in sums file '%s'", number, file)
+		}
+		// name may start with '*' for binary mode
+		name := strings.TrimPrefix(strings.TrimLeft(line[index:], " "), "*")
+		sums[name] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading sums file: %v", err)
+	}
+	return sums, nil
+}
+
+func fileChecksum(file string, newHash func() hash.Hash) (string, error) {
+	stream, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("opening file to checksum: %v", err)
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	hasher := newHash()
+	if _, err := io.Copy(hasher, stream); err != nil {
+		return "", fmt.Errorf("computing checksum of '%s': %v", file, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -18,7 +18,7 @@ import (
 
 const (
 	// DefaultRepository is default repository location
-	DefaultRepository = "http://central.maven.org/maven2"
+	DefaultRepository = "https://repo1.maven.org/maven2"
 )
 
 // LocalRepository is default location for local repository
@@ -42,8 +42,17 @@ Arguments:
 - scopes: classpath scope (strings, optional, wrap). If set, will take
   dependencies without scope and listed scopes, if not set, will only take
   dependencies without scope).
+- poms: Maven POM files of projects to add dependencies of, with their
+  transitive dependencies (strings, optional, file, wrap).
+- artifacts: Maven artifacts to add, as 'group:artifact:version', with their
+  transitive dependencies (strings, optional, wrap).
+- transitive: tells if transitive dependencies of dependency files should be
+  added, defaults to false (boolean, optional).
 - repositories: repository URLs to get dependencies from, defaults to
-  'http://repo1.maven.org/maven2' (strings, optional, wrap).
+  'https://repo1.maven.org/maven2' (strings, optional, wrap).
+- local: local repository directory, defaults to '~/.m2/repository' for Maven
+  resolution and '~/.java/repository' for dependency files otherwise (string,
+  optional, file).
 - todir: directory to copy jar files into (string, optional, file).
 
 Examples:
@@ -57,6 +66,14 @@ Examples:
     # build classpath with a dependencies file
     - classpath:    'classpath'
       dependencies: 'dependencies.yml'
+    # build classpath with dependencies of a Maven project
+    - classpath: 'classpath'
+      poms:      'pom.xml'
+      scopes:    ['runtime', 'test']
+    # build classpath with an artifact and its dependencies, offline
+    - classpath:    'classpath'
+      artifacts:    'org.apache.commons:commons-text:1.10.0'
+      repositories: 'file:///opt/maven/repository'
     # copy classpath's jar files to 'build/lib' directory
     - classpath:    _
       dependencies: 'dependencies.yml'
@@ -72,7 +89,18 @@ Notes:
       scopes:   [test]
 
 - Scopes are optional. If not set, dependency will always be included. If set,
-  dependency will be included for classpath with these scopes.`,
+  dependency will be included for classpath with these scopes.
+- Maven resolution (with poms, artifacts or transitive) reads POM files of
+  dependencies, with their parents, properties, dependency management (with
+  imported BOMs), scopes, optional dependencies and exclusions. Version
+  conflicts are resolved as Maven does, nearest dependency wins. Dependencies
+  with scope 'compile' are always included, those with scopes 'runtime', 'test'
+  and 'provided' only if listed in scopes. System dependencies and version
+  ranges are not supported.
+- Maven local repository has the same layout as '~/.m2/repository' and may be
+  shared with Maven.
+- Repositories may be HTTP URLs or 'file://' URLs of repositories on disk to
+  work offline.`,
 	})
 }
 
@@ -82,7 +110,11 @@ type classpathArgs struct {
 	Jars         []string `neon:"optional,file,wrap"`
 	Dependencies []string `neon:"optional,file,wrap"`
 	Scopes       []string `neon:"optional,wrap"`
+	Poms         []string `neon:"optional,file,wrap"`
+	Artifacts    []string `neon:"optional,wrap"`
+	Transitive   bool     `neon:"optional"`
 	Repositories []string `neon:"optional,wrap"`
+	Local        string   `neon:"optional,file"`
 	Todir        string   `neon:"optional,file"`
 }
 
@@ -98,7 +130,18 @@ func classpath(context *build.Context, args interface{}) error {
 			return fmt.Errorf("getting jars files: %v", err)
 		}
 	}
-	deps, err := getDependencies(params.Dependencies, params.Scopes, params.Repositories, context)
+	var deps []string
+	if params.Transitive {
+		deps, err = getMavenDependencies(params, context)
+	} else {
+		deps, err = getDependencies(params.Dependencies, params.Scopes, params.Repositories, params.Local, context)
+		if err == nil && (len(params.Poms) > 0 || len(params.Artifacts) > 0) {
+			var maven []string
+			params.Dependencies = nil
+			maven, err = getMavenDependencies(params, context)
+			deps = append(deps, maven...)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("getting dependencies: %v", err)
 	}
@@ -125,15 +168,61 @@ func classpath(context *build.Context, args interface{}) error {
 	return nil
 }
 
-func getDependencies(dependencies, scopes, repositories []string, context *build.Context) ([]string, error) {
-	if !util.DirExists(LocalRepository) {
-		if err := os.MkdirAll(LocalRepository, util.DirFileMode); err != nil {
+// getMavenDependencies resolves dependencies of POM files, artifacts and
+// dependency files with their transitive dependencies
+func getMavenDependencies(params classpathArgs, context *build.Context) ([]string, error) {
+	resolver := newMavenResolver(context, params.Local, params.Repositories)
+	var roots []pomDependency
+	managed := make(map[string]pomDependency)
+	for _, file := range params.Poms {
+		dependencies, management, err := resolver.projectDependencies(file)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, dependencies...)
+		for key, dependency := range management {
+			if _, ok := managed[key]; !ok {
+				managed[key] = dependency
+			}
+		}
+	}
+	for _, artifact := range params.Artifacts {
+		dependency, err := parseArtifact(artifact)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, dependency)
+	}
+	for _, file := range params.Dependencies {
+		dependencies, err := readDependencies(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, dependency := range dependencies {
+			if selected(params.Scopes, dependency.Scopes) {
+				roots = append(roots, pomDependency{
+					GroupID:    dependency.Group,
+					ArtifactID: dependency.Artifact,
+					Version:    dependency.Version,
+				})
+			}
+		}
+	}
+	return resolver.resolve(roots, managed, params.Scopes)
+}
+
+func getDependencies(dependencies, scopes, repositories []string, local string, context *build.Context) ([]string, error) {
+	if local == "" {
+		local = LocalRepository
+	}
+	if !util.DirExists(local) {
+		if err := os.MkdirAll(local, util.DirFileMode); err != nil {
 			return nil, err
 		}
 	}
 	var deps []string
 	for _, dependency := range dependencies {
-		dep, err := getDependency(dependency, scopes, repositories, context)
+		dep, err := getDependency(dependency, scopes, repositories, local, context)
 		if err != nil {
 			return nil, err
 		}
@@ -142,7 +231,7 @@ func getDependencies(dependencies, scopes, repositories []string, context *build
 	return deps, nil
 }
 
-func getDependency(file string, scopes, repositories []string, context *build.Context) ([]string, error) {
+func readDependencies(file string) (dependencies, error) {
 	var dependencies dependencies
 	source, err := os.ReadFile(file)
 	if err != nil {
@@ -152,13 +241,21 @@ func getDependency(file string, scopes, repositories []string, context *build.Co
 	if err != nil {
 		return nil, err
 	}
+	return dependencies, nil
+}
+
+func getDependency(file string, scopes, repositories []string, local string, context *build.Context) ([]string, error) {
+	dependencies, err := readDependencies(file)
+	if err != nil {
+		return nil, err
+	}
 	var paths []string
 	for _, dependency := range dependencies {
 		if selected(scopes, dependency.Scopes) {
-			path := dependency.Path(LocalRepository)
+			path := dependency.Path(local)
 			paths = append(paths, path)
 			if !util.FileExists(path) {
-				err = downloadDependency(dependency, repositories, context)
+				err = downloadDependency(dependency, repositories, local, context)
 				if err != nil {
 					return nil, err
 				}
@@ -171,9 +268,9 @@ func getDependency(file string, scopes, repositories []string, context *build.Co
 	return paths, nil
 }
 
-func downloadDependency(dependency dependency, repositories []string, context *build.Context) error {
+func downloadDependency(dependency dependency, repositories []string, local string, context *build.Context) error {
 	context.MessageArgs("Downloading dependency '%s'", dependency.String())
-	path := dependency.Path(LocalRepository)
+	path := dependency.Path(local)
 	dir := filepath.Dir(path)
 	if !util.DirExists(dir) {
 		if err := os.MkdirAll(dir, util.DirFileMode); err != nil {
@@ -186,7 +283,7 @@ func downloadDependency(dependency dependency, repositories []string, context *b
 	var err error
 	for _, repository := range repositories {
 		url := dependency.Path(repository)
-		err = download(path, url)
+		err = fetchFile(path, url)
 		if err == nil {
 			return nil
 		}
@@ -194,6 +291,9 @@ func downloadDependency(dependency dependency, repositories []string, context *b
 	return err
 }
 
+// download gets a file from an HTTP URL. File is downloaded in a temporary
+// file renamed when complete, so that a failed download leaves no truncated
+// file in repository.
 func download(path, url string) error {
 	response, err := http.Get(url)
 	if err != nil {
@@ -205,16 +305,26 @@ func download(path, url string) error {
 	if response.StatusCode != 200 {
 		return fmt.Errorf("getting '%s': %s", url, response.Status)
 	}
-	output, err := os.Create(path)
+	return writeAtomically(path, response.Body)
+}
+
+// writeAtomically writes content of reader in a temporary file that is
+// renamed to path when complete
+func writeAtomically(path string, reader io.Reader) error {
+	output, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
-		return fmt.Errorf("saving dependency '%s': %v", path, err)
+		return fmt.Errorf("saving '%s': %v", path, err)
+	}
+	_, err = io.Copy(output, reader)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(output.Name(), path)
 	}
-	defer func() {
-		_ = output.Close()
-	}()
-	_, err = io.Copy(output, response.Body)
 	if err != nil {
-		return fmt.Errorf("saving dependency '%s': %v", path, err)
+		_ = os.Remove(output.Name())
+		return fmt.Errorf("saving '%s': %v", path, err)
 	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"fmt"
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+	"os"
+	"reflect"
+	"sort"
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "edit",
+		Func: edit,
+		Args: reflect.TypeOf(editArgs{}),
+		Help: `Edit values in a YAML, JSON or TOML file.
+
+Arguments:
+
+- edit: the file to edit (string, file).
+- set: values to set by path (map, optional).
+- unset: paths of values to delete (strings, optional, wrap).
+- format: format of the file, 'yaml', 'json' or 'toml', guessed from file
+  extension if not set (string, optional).
+
+Examples:
+
+    # set version in package.json
+    - edit: 'package.json'
+      set:
+        version: '={VERSION}'
+    # set version of first dependency and delete annotations in Chart.yaml
+    - edit: 'Chart.yaml'
+      set:
+        'dependencies[0].version': '1.2.3'
+      unset: 'annotations'
+
+Notes:
+
+- Paths have keys separated with dots and indexes in brackets (such as
+  'a.b[2].c'). Keys with dots are quoted in brackets (such as 'a["b.c"]').
+- Missing keys are created when setting values, and an index may be the length
+  of a list to append a value. Unsetting a missing path does nothing.
+- Values are set in order of paths, then values are unset.
+- Order of keys is kept. YAML files keep their comments and style of values,
+  but lists in maps are written back indented. JSON files are written back
+  with their indentation. TOML files are edited line by line to keep their formatting, but
+  indexes, inline tables, multi-line values and arrays of tables are not
+  supported, and keys can't be added to tables defined with dotted keys.`,
+	})
+}
+
+type editArgs struct {
+	Edit   string                      `neon:"file"`
+	Set    map[interface{}]interface{} `neon:"optional"`
+	Unset  []string                    `neon:"optional,wrap"`
+	Format string                      `neon:"optional"`
+}
+
+func edit(context *build.Context, args interface{}) error {
+	params := args.(editArgs)
+	format, err := util.DataFormat(params.Edit, params.Format)
+	if err != nil {
+		return err
+	}
+	var edits []util.DataEdit
+	for path, value := range params.Set {
+		edits = append(edits, util.DataEdit{Path: fmt.Sprint(path), Value: value})
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Path < edits[j].Path
+	})
+	for _, path := range params.Unset {
+		edits = append(edits, util.DataEdit{Path: path, Unset: true})
+	}
+	info, err := os.Stat(params.Edit)
+	if err != nil {
+		return fmt.Errorf("getting mode of file to edit: %v", err)
+	}
+	content, err := os.ReadFile(params.Edit)
+	if err != nil {
+		return fmt.Errorf("reading file to edit: %v", err)
+	}
+	context.MessageArgs("Editing %d value(s) in '%s'", len(edits), params.Edit)
+	edited, err := util.EditData(content, format, edits)
+	if err != nil {
+		return fmt.Errorf("editing file '%s': %v", params.Edit, err)
+	}
+	if err := os.WriteFile(params.Edit, edited, info.Mode()); err != nil {
+		return fmt.Errorf("writing edited file: %v", err)
+	}
+	return nil
+}
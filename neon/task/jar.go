@@ -0,0 +1,270 @@
+package task
+
+import (
+	z "archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	t "time"
+
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+)
+
+const (
+	// ManifestPath is the path of the manifest in jar files
+	ManifestPath = "META-INF/MANIFEST.MF"
+	// ServicesPath is the directory of service providers in jar files
+	ServicesPath = "META-INF/services/"
+	// maximum length of a manifest line, in bytes
+	manifestLineLength = 72
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "jar",
+		Func: jar,
+		Args: reflect.TypeOf(jarArgs{}),
+		Help: `Create a Java archive.
+
+Arguments:
+
+- jar: globs of class and resource files to add in archive (strings, file,
+  wrap).
+- dir: root directory for globs, such as classes directory (string, optional,
+  file).
+- exclude: globs of files to exclude (strings, optional, file, wrap).
+- tofile: name of the jar file to create (string, file).
+- main: main class, written as Main-Class in manifest (string, optional).
+- cp: classpath of the application, such as built by classpath task, written
+  as Class-Path in manifest (string, optional).
+- cpprefix: directory of jar files of Class-Path, relative to the jar file
+  (string, optional).
+- manifest: other manifest entries (map with string keys and values,
+  optional).
+- services: service providers, implementation classes by service interface
+  (map with string keys and string or list of strings values, optional).
+- reproducible: create a reproducible archive, with fixed modification times
+  and sorted entries (boolean, optional).
+
+Examples:
+
+    # create an executable jar with classes in build/classes
+    - jar:    '**/*'
+      dir:    'build/classes'
+      tofile: 'build/app.jar'
+      main:   'foo.Main'
+    # create a reproducible jar with dependencies in lib directory
+    - classpath:    'classpath'
+      dependencies: 'dependencies.yml'
+      todir:        'build/lib'
+    - jar:          '**/*'
+      dir:          'build/classes'
+      tofile:       'build/app.jar'
+      main:         'foo.Main'
+      cp:           =classpath
+      cpprefix:     'lib'
+      reproducible: true
+    # declare a service provider
+    - jar:    '**/*.class'
+      dir:    'build/classes'
+      tofile: 'build/plugin.jar'
+      services:
+        foo.Plugin: ['foo.bar.BarPlugin', 'foo.baz.BazPlugin']
+
+Notes:
+
+- Class-Path entries are names of the jar files of the classpath, prefixed with
+  cpprefix if set. Directories of the classpath are ignored.
+- Service provider files are written in META-INF/services directory.
+- In reproducible archives, modification time of files is taken from
+  SOURCE_DATE_EPOCH environment variable if set, or January 1, 1980 otherwise.`,
+	})
+}
+
+type jarArgs struct {
+	Jar          []string                    `neon:"file,wrap"`
+	Dir          string                      `neon:"optional,file"`
+	Exclude      []string                    `neon:"optional,file,wrap"`
+	Tofile       string                      `neon:"file"`
+	Main         string                      `neon:"optional"`
+	Cp           string                      `neon:"optional"`
+	Cpprefix     string                      `neon:"optional"`
+	Manifest     map[string]string           `neon:"optional"`
+	Services     map[interface{}]interface{} `neon:"optional"`
+	Reproducible bool                        `neon:"optional"`
+}
+
+func jar(context *build.Context, args interface{}) error {
+	params := args.(jarArgs)
+	files, err := util.FindFiles(params.Dir, params.Jar, params.Exclude, false)
+	if err != nil {
+		return fmt.Errorf("getting files for jar task: %v", err)
+	}
+	services, err := jarServices(params.Services)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := SanitizeName(file)
+		if _, ok := services[name]; ok || name == ManifestPath {
+			return fmt.Errorf("file '%s' would override generated one", name)
+		}
+	}
+	mtime := t.Now()
+	if params.Reproducible {
+		mtime, err = reproducibleTime()
+		if err != nil {
+			return err
+		}
+		sorted := append([]string(nil), files...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return SanitizeName(sorted[i]) < SanitizeName(sorted[j])
+		})
+		files = sorted
+	}
+	context.MessageArgs("Archiving %d file(s) in '%s'", len(files), params.Tofile)
+	if dir := filepath.Dir(params.Tofile); !util.DirExists(dir) {
+		if err := os.MkdirAll(dir, util.DirFileMode); err != nil {
+			return fmt.Errorf("making jar directory: %v", err)
+		}
+	}
+	archive, err := os.Create(params.Tofile)
+	if err != nil {
+		return fmt.Errorf("creating jar file: %v", err)
+	}
+	defer func() {
+		_ = archive.Close()
+	}()
+	zipper := z.NewWriter(archive)
+	// manifest must be first entry of the archive
+	manifest := jarManifest(params.Main, params.Cp, params.Cpprefix, params.Manifest)
+	if err := writeJarEntry(zipper, ManifestPath, manifest, mtime); err != nil {
+		return fmt.Errorf("writing manifest: %v", err)
+	}
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeJarEntry(zipper, name, services[name], mtime); err != nil {
+			return fmt.Errorf("writing service provider: %v", err)
+		}
+	}
+	var entryTime *t.Time
+	if params.Reproducible {
+		entryTime = &mtime
+	}
+	for _, file := range files {
+		path := file
+		if params.Dir != "" {
+			path = filepath.Join(params.Dir, file)
+		}
+		if err := writeFileToZip(zipper, path, file, "", false, entryTime, z.Deflate); err != nil {
+			return fmt.Errorf("writing file to jar: %v", err)
+		}
+	}
+	if err := zipper.Close(); err != nil {
+		return fmt.Errorf("writing jar file: %v", err)
+	}
+	return nil
+}
+
+// jarServices returns content of service provider files by path in archive
+func jarServices(services map[interface{}]interface{}) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for key, value := range services {
+		service, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("service name must be a string")
+		}
+		var providers []string
+		switch v := value.(type) {
+		case string:
+			providers = []string{v}
+		case []interface{}:
+			for _, provider := range v {
+				name, ok := provider.(string)
+				if !ok {
+					return nil, fmt.Errorf("providers of service '%s' must be strings", service)
+				}
+				providers = append(providers, name)
+			}
+		default:
+			return nil, fmt.Errorf("providers of service '%s' must be a string or list of strings", service)
+		}
+		files[ServicesPath+service] = []byte(strings.Join(providers, "\n") + "\n")
+	}
+	return files, nil
+}
+
+// jarManifest returns the content of a manifest
+func jarManifest(main, classpath, prefix string, entries map[string]string) []byte {
+	var manifest bytes.Buffer
+	writeManifestEntry(&manifest, "Manifest-Version", "1.0")
+	writeManifestEntry(&manifest, "Created-By", "neon")
+	if main != "" {
+		writeManifestEntry(&manifest, "Main-Class", main)
+	}
+	if classpath != "" {
+		var jars []string
+		for _, element := range filepath.SplitList(classpath) {
+			if strings.HasSuffix(element, ".jar") {
+				jar := filepath.Base(element)
+				if prefix != "" {
+					jar = strings.TrimSuffix(filepath.ToSlash(prefix), "/") + "/" + jar
+				}
+				jars = append(jars, jar)
+			}
+		}
+		if len(jars) > 0 {
+			writeManifestEntry(&manifest, "Class-Path", strings.Join(jars, " "))
+		}
+	}
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeManifestEntry(&manifest, name, entries[name])
+	}
+	manifest.WriteString("\r\n")
+	return manifest.Bytes()
+}
+
+// writeManifestEntry writes an entry, wrapping lines longer than 72 bytes
+// with continuation lines starting with a space
+func writeManifestEntry(manifest *bytes.Buffer, name, value string) {
+	line := name + ": " + value
+	for len(line) > manifestLineLength {
+		cut := manifestLineLength
+		// don't split UTF-8 characters
+		for cut > 1 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		manifest.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+	manifest.WriteString(line + "\r\n")
+}
+
+func writeJarEntry(zipper *z.Writer, name string, content []byte, mtime t.Time) error {
+	header := &z.FileHeader{
+		Name:     name,
+		Method:   z.Deflate,
+		Modified: mtime,
+	}
+	header.SetMode(FileMode)
+	writer, err := zipper.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
@@ -0,0 +1,208 @@
+package task
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+const (
+	// JUnitLauncher is the artifact of JUnit Platform console launcher
+	JUnitLauncher = "org.junit.platform:junit-platform-console-standalone"
+	// DefaultJUnitVersion is the default version of JUnit Platform
+	DefaultJUnitVersion = "1.10.2"
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "junit",
+		Func: junit,
+		Args: reflect.TypeOf(junitArgs{}),
+		Help: `Run Java tests with JUnit Platform console launcher.
+
+Arguments:
+
+- junit: directories of test classes to scan for tests (strings, file, wrap).
+- cp: classpath of tests, test classes directories are added to it (string,
+  optional).
+- include: regular expressions of names of test classes to run, defaults to
+  JUnit ones (strings, optional, wrap).
+- tags: tags of tests to run (strings, optional, wrap).
+- reports: directory of XML reports (string, optional, file).
+- launcher: jar file of console launcher, downloaded from repositories if not
+  set (string, optional, file).
+- version: version of JUnit Platform to download, defaults to '1.10.2'
+  (string, optional).
+- repositories: repository URLs to download console launcher from, defaults to
+  'https://repo1.maven.org/maven2' (strings, optional, wrap).
+- jvm: options of the Java virtual machine (strings, optional, wrap).
+- args: other arguments of the console launcher (strings, optional, wrap).
+
+Examples:
+
+    # run tests in build/test-classes with XML reports in build/reports
+    - classpath:    'classpath'
+      classes:      'build/classes'
+      dependencies: 'dependencies.yml'
+      scopes:       'test'
+    - junit:   'build/test-classes'
+      cp:      =classpath
+      reports: 'build/reports'
+    # run tests tagged 'fast' with a given launcher
+    - junit:    'build/test-classes'
+      cp:       =classpath
+      tags:     'fast'
+      launcher: 'lib/junit-platform-console-standalone.jar'
+
+Notes:
+
+- Console launcher must be version 1.10 or later.
+- XML reports of previous runs (files TEST-*.xml) are removed from reports
+  directory before running tests.
+- When reports are written, property _tests is set with counts of tests,
+  failures, errors and skipped tests found in XML reports, as a map with keys
+  'tests', 'failures', 'errors' and 'skipped'.`,
+	})
+}
+
+type junitArgs struct {
+	Junit        []string `neon:"file,wrap"`
+	Cp           string   `neon:"optional"`
+	Include      []string `neon:"optional,wrap"`
+	Tags         []string `neon:"optional,wrap"`
+	Reports      string   `neon:"optional,file"`
+	Launcher     string   `neon:"optional,file"`
+	Version      string   `neon:"optional"`
+	Repositories []string `neon:"optional,wrap"`
+	Jvm          []string `neon:"optional,wrap"`
+	Args         []string `neon:"optional,wrap"`
+}
+
+func junit(context *build.Context, args interface{}) error {
+	params := args.(junitArgs)
+	launcher := params.Launcher
+	if launcher == "" {
+		version := params.Version
+		if version == "" {
+			version = DefaultJUnitVersion
+		}
+		artifact, err := parseArtifact(JUnitLauncher + ":" + version)
+		if err != nil {
+			return err
+		}
+		resolver := newMavenResolver(context, "", params.Repositories)
+		paths, err := resolver.resolve([]pomDependency{artifact}, nil, nil)
+		if err != nil {
+			return fmt.Errorf("getting console launcher: %v", err)
+		}
+		launcher = paths[0]
+	}
+	classpath := append([]string(nil), params.Junit...)
+	if params.Cp != "" {
+		classpath = append(classpath, params.Cp)
+	}
+	options := append([]string(nil), params.Jvm...)
+	options = append(options, "-jar", launcher, "execute", "--disable-banner",
+		"--class-path", strings.Join(classpath, string(os.PathListSeparator)),
+		"--scan-class-path="+strings.Join(params.Junit, string(os.PathListSeparator)))
+	for _, include := range params.Include {
+		options = append(options, "--include-classname", include)
+	}
+	for _, tag := range params.Tags {
+		options = append(options, "--include-tag", tag)
+	}
+	if params.Reports != "" {
+		options = append(options, "--reports-dir", params.Reports)
+	}
+	options = append(options, params.Args...)
+	if params.Reports != "" {
+		if err := clearJUnitReports(params.Reports); err != nil {
+			return fmt.Errorf("removing previous test reports: %v", err)
+		}
+	}
+	context.MessageArgs("Running tests in %s", strings.Join(params.Junit, ", "))
+	command := exec.Command("java", options...)
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current working directory: %v", err)
+	}
+	command.Dir = dir
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	err = command.Run()
+	if params.Reports != "" {
+		counts, reportErr := junitReports(params.Reports)
+		if reportErr != nil {
+			return fmt.Errorf("reading test reports: %v", reportErr)
+		}
+		context.SetProperty("_tests", counts)
+		context.MessageArgs("Tests: %d, failures: %d, errors: %d, skipped: %d",
+			counts["tests"], counts["failures"], counts["errors"], counts["skipped"])
+	}
+	if err != nil {
+		return fmt.Errorf("running tests: %v", err)
+	}
+	return nil
+}
+
+// junitSuite is a test suite in a JUnit XML report
+type junitSuite struct {
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// clearJUnitReports removes XML reports of previous runs in a directory, so
+// that they are not counted with new ones
+func clearJUnitReports(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "TEST-*.xml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// junitReports returns counts of tests in XML reports of a directory
+func junitReports(dir string) (map[string]int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "TEST-*.xml"))
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{"tests": 0, "failures": 0, "errors": 0, "skipped": 0}
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var suite junitSuite
+		if err := xml.Unmarshal(source, &suite); err != nil {
+			return nil, fmt.Errorf("parsing report '%s': %v", file, err)
+		}
+		// reports may have a testsuites root element
+		suites := suite.Suites
+		if len(suites) == 0 {
+			suites = []junitSuite{suite}
+		}
+		for _, suite := range suites {
+			counts["tests"] += suite.Tests
+			counts["failures"] += suite.Failures
+			counts["errors"] += suite.Errors
+			counts["skipped"] += suite.Skipped
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJUnitReports(t *testing.T) {
+	dir := t.TempDir()
+	jupiter := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="JUnit Jupiter" tests="5" skipped="1" failures="1" errors="0">
+<testcase name="test()" classname="foo.FooTest"/>
+</testsuite>`
+	vintage := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+<testsuite name="JUnit Vintage" tests="3" skipped="0" failures="0" errors="1"/>
+<testsuite name="Other" tests="2" skipped="0" failures="1" errors="0"/>
+</testsuites>`
+	for name, content := range map[string]string{"TEST-junit-jupiter.xml": jupiter, "TEST-junit-vintage.xml": vintage, "other.xml": "bad"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), FileMode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, err := junitReports(dir)
+	if err != nil {
+		t.Fatalf("reading reports: %v", err)
+	}
+	if counts["tests"] != 10 || counts["failures"] != 2 || counts["errors"] != 1 || counts["skipped"] != 1 {
+		t.Errorf("bad counts: %v", counts)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "TEST-bad.xml"), []byte("bad"), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := junitReports(dir); err == nil {
+		t.Errorf("reading bad report should fail")
+	}
+	// reports of previous runs are removed, other files are kept
+	if err := clearJUnitReports(dir); err != nil {
+		t.Fatalf("removing reports: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != "other.xml" {
+		t.Errorf("bad files after removing reports: %v", files)
+	}
+}
@@ -0,0 +1,544 @@
+package task
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+)
+
+// MavenRepository is default location for local repository of Maven artifacts
+var MavenRepository = util.ExpandUserHome("~/.m2/repository")
+
+// maximum depth of POM parents and property references
+const mavenMaxDepth = 32
+
+// regexp for property references in POM files
+var pomPropertyRef = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// pom is a Maven POM file as parsed from XML
+type pom struct {
+	Parent               *pomParent      `xml:"parent"`
+	GroupID              string          `xml:"groupId"`
+	ArtifactID           string          `xml:"artifactId"`
+	Version              string          `xml:"version"`
+	Properties           pomProperties   `xml:"properties"`
+	DependencyManagement []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies         []pomDependency `xml:"dependencies>dependency"`
+}
+
+type pomParent struct {
+	GroupID      string  `xml:"groupId"`
+	ArtifactID   string  `xml:"artifactId"`
+	Version      string  `xml:"version"`
+	RelativePath *string `xml:"relativePath"`
+}
+
+type pomDependency struct {
+	GroupID    string         `xml:"groupId"`
+	ArtifactID string         `xml:"artifactId"`
+	Version    string         `xml:"version"`
+	Type       string         `xml:"type"`
+	Classifier string         `xml:"classifier"`
+	Scope      string         `xml:"scope"`
+	Optional   string         `xml:"optional"`
+	Exclusions []pomExclusion `xml:"exclusions>exclusion"`
+}
+
+type pomExclusion struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+}
+
+// pomProperties are properties of a POM, with any element name
+type pomProperties map[string]string
+
+// UnmarshalXML parses properties with element names as keys
+func (p *pomProperties) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	*p = make(pomProperties)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := decoder.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			(*p)[element.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// key returns the key identifying an artifact, regardless of its version
+func (d pomDependency) key() string {
+	return d.GroupID + ":" + d.ArtifactID + ":" + d.extension() + ":" + d.Classifier
+}
+
+func (d pomDependency) String() string {
+	return d.GroupID + ":" + d.ArtifactID + ":" + d.Version
+}
+
+// extension returns the extension of the artifact file for dependency type
+func (d pomDependency) extension() string {
+	switch d.Type {
+	case "", "jar", "bundle", "test-jar", "ejb", "maven-plugin":
+		return "jar"
+	}
+	return d.Type
+}
+
+// excludes tells if an exclusion matches given dependency
+func (e pomExclusion) excludes(dependency pomDependency) bool {
+	return (e.GroupID == "*" || e.GroupID == dependency.GroupID) &&
+		(e.ArtifactID == "*" || e.ArtifactID == dependency.ArtifactID)
+}
+
+// excluded tells if a dependency matches one of exclusions
+func excluded(dependency pomDependency, exclusions []pomExclusion) bool {
+	for _, exclusion := range exclusions {
+		if exclusion.excludes(dependency) {
+			return true
+		}
+	}
+	return false
+}
+
+// pomModel is a POM merged with its parents
+type pomModel struct {
+	group        string
+	artifact     string
+	version      string
+	parent       string
+	properties   map[string]string
+	managed      []pomDependency
+	dependencies []pomDependency
+}
+
+// effectivePom is a POM model with interpolated and managed dependencies
+type effectivePom struct {
+	managed      map[string]pomDependency
+	dependencies []pomDependency
+}
+
+// mavenResolver resolves Maven dependencies with their POM files
+type mavenResolver struct {
+	context      *build.Context
+	local        string
+	repositories []string
+	models       map[string]*pomModel
+	effectives   map[string]*effectivePom
+}
+
+func newMavenResolver(context *build.Context, local string, repositories []string) *mavenResolver {
+	if local == "" {
+		local = MavenRepository
+	}
+	if repositories == nil {
+		repositories = []string{DefaultRepository}
+	}
+	return &mavenResolver{
+		context:      context,
+		local:        local,
+		repositories: repositories,
+		models:       make(map[string]*pomModel),
+		effectives:   make(map[string]*effectivePom),
+	}
+}
+
+// parseArtifact parses Maven coordinates 'group:artifact:version'
+func parseArtifact(coordinates string) (pomDependency, error) {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return pomDependency{}, fmt.Errorf("bad artifact coordinates '%s', should be 'group:artifact:version'", coordinates)
+	}
+	return pomDependency{GroupID: parts[0], ArtifactID: parts[1], Version: parts[2]}, nil
+}
+
+// projectDependencies returns dependencies of a project POM file with its
+// dependency management
+func (r *mavenResolver) projectDependencies(file string) ([]pomDependency, map[string]pomDependency, error) {
+	model, err := r.fileModel(file, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	effective, err := r.effective(model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("in POM file '%s': %v", file, err)
+	}
+	return effective.dependencies, effective.managed, nil
+}
+
+// resolve returns paths of jar files for dependencies and their transitive
+// dependencies in selected scopes. Conflicts are resolved as Maven does,
+// nearest dependency wins.
+func (r *mavenResolver) resolve(roots []pomDependency, managed map[string]pomDependency, scopes []string) ([]string, error) {
+	type node struct {
+		dependency pomDependency
+		exclusions []pomExclusion
+	}
+	var queue []node
+	for _, root := range roots {
+		if root.Scope == "" {
+			root.Scope = "compile"
+		}
+		queue = append(queue, node{dependency: root, exclusions: root.Exclusions})
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		dependency := current.dependency
+		if !selectedScope(dependency.Scope, scopes) || seen[dependency.key()] {
+			continue
+		}
+		seen[dependency.key()] = true
+		version, err := pomVersion(dependency)
+		if err != nil {
+			return nil, err
+		}
+		dependency.Version = version
+		if dependency.Type != "pom" {
+			path, err := r.fetch(artifactPath(dependency, dependency.extension(), dependency.Classifier))
+			if err != nil {
+				return nil, fmt.Errorf("getting artifact '%s': %v", dependency, err)
+			}
+			paths = append(paths, path)
+		}
+		model, err := r.artifactModel(dependency.GroupID, dependency.ArtifactID, dependency.Version, 0)
+		if err != nil {
+			return nil, err
+		}
+		effective, err := r.effective(model)
+		if err != nil {
+			return nil, fmt.Errorf("in POM of '%s': %v", dependency, err)
+		}
+		for _, child := range effective.dependencies {
+			if child.Optional == "true" || child.Scope == "test" || child.Scope == "provided" ||
+				child.Scope == "system" || excluded(child, current.exclusions) {
+				continue
+			}
+			if management, ok := managed[child.key()]; ok {
+				if management.Version != "" {
+					child.Version = management.Version
+				}
+				if management.Scope != "" {
+					child.Scope = management.Scope
+				}
+			}
+			child.Scope = transitiveScope(dependency.Scope, child.Scope)
+			exclusions := append(append([]pomExclusion(nil), current.exclusions...), child.Exclusions...)
+			queue = append(queue, node{dependency: child, exclusions: exclusions})
+		}
+	}
+	return paths, nil
+}
+
+// selectedScope tells if dependency scope is selected by classpath scopes
+func selectedScope(scope string, scopes []string) bool {
+	if scope == "" || scope == "compile" {
+		return true
+	}
+	if scope == "system" || scope == "import" {
+		return false
+	}
+	for _, selected := range scopes {
+		if selected == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// transitiveScope returns the scope of a transitive dependency
+func transitiveScope(parent, child string) string {
+	if parent == "compile" {
+		if child == "" {
+			return "compile"
+		}
+		return child
+	}
+	return parent
+}
+
+// pomVersion returns the version of a dependency, supporting fixed ranges
+func pomVersion(dependency pomDependency) (string, error) {
+	version := dependency.Version
+	if version == "" {
+		return "", fmt.Errorf("no version for dependency '%s:%s'", dependency.GroupID, dependency.ArtifactID)
+	}
+	if strings.HasPrefix(version, "[") && strings.HasSuffix(version, "]") && !strings.Contains(version, ",") {
+		return version[1 : len(version)-1], nil
+	}
+	if strings.ContainsAny(version, "[](),") {
+		return "", fmt.Errorf("version range '%s' of dependency '%s:%s' is not supported", version, dependency.GroupID, dependency.ArtifactID)
+	}
+	return version, nil
+}
+
+// artifactPath returns path of an artifact file relative to repository root
+func artifactPath(dependency pomDependency, extension, classifier string) string {
+	name := dependency.ArtifactID + "-" + dependency.Version
+	if classifier != "" {
+		name += "-" + classifier
+	} else if dependency.Type == "test-jar" {
+		name += "-tests"
+	}
+	return strings.ReplaceAll(dependency.GroupID, ".", "/") + "/" + dependency.ArtifactID + "/" + dependency.Version + "/" + name + "." + extension
+}
+
+// fetch returns local path of a repository file, downloading it if necessary
+func (r *mavenResolver) fetch(path string) (string, error) {
+	local := filepath.Join(r.local, filepath.FromSlash(path))
+	if util.FileExists(local) {
+		return local, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(local), util.DirFileMode); err != nil {
+		return "", err
+	}
+	r.context.MessageArgs("Downloading '%s'", path)
+	var err error
+	for _, repository := range r.repositories {
+		url := strings.TrimSuffix(repository, "/") + "/" + path
+		if err = fetchFile(local, url); err == nil {
+			return local, nil
+		}
+	}
+	return "", err
+}
+
+// fetchFile gets a file from an HTTP or file URL into given path
+func fetchFile(path, url string) error {
+	if !strings.HasPrefix(url, "file://") {
+		return download(path, url)
+	}
+	source, err := os.Open(strings.TrimPrefix(url, "file://"))
+	if err != nil {
+		return fmt.Errorf("getting '%s': %v", url, err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	return writeAtomically(path, source)
+}
+
+// artifactModel returns the model of an artifact, downloading its POM
+func (r *mavenResolver) artifactModel(group, artifact, version string, depth int) (*pomModel, error) {
+	id := group + ":" + artifact + ":" + version
+	if model, ok := r.models[id]; ok {
+		return model, nil
+	}
+	dependency := pomDependency{GroupID: group, ArtifactID: artifact, Version: version}
+	file, err := r.fetch(artifactPath(dependency, "pom", ""))
+	if err != nil {
+		return nil, fmt.Errorf("getting POM of '%s': %v", id, err)
+	}
+	model, err := r.fileModel(file, depth)
+	if err != nil {
+		return nil, err
+	}
+	r.models[id] = model
+	return model, nil
+}
+
+// fileModel parses a POM file and merges it with its parents
+func (r *mavenResolver) fileModel(file string, depth int) (*pomModel, error) {
+	if depth > mavenMaxDepth {
+		return nil, fmt.Errorf("too many parents for POM file '%s'", file)
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading POM file: %v", err)
+	}
+	var project pom
+	if err := xml.Unmarshal(source, &project); err != nil {
+		return nil, fmt.Errorf("parsing POM file '%s': %v", file, err)
+	}
+	parent := &pomModel{properties: map[string]string{}}
+	if project.Parent != nil {
+		parent, err = r.parentModel(file, project.Parent, depth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inheritModel(&project, parent), nil
+}
+
+// parentModel returns model of parent POM, looking for it in relative path
+// first and in repositories then
+func (r *mavenResolver) parentModel(file string, parent *pomParent, depth int) (*pomModel, error) {
+	relative := "../pom.xml"
+	if parent.RelativePath != nil {
+		relative = strings.TrimSpace(*parent.RelativePath)
+	}
+	if relative != "" {
+		path := filepath.Join(filepath.Dir(file), filepath.FromSlash(relative))
+		if util.DirExists(path) {
+			path = filepath.Join(path, "pom.xml")
+		}
+		if util.FileExists(path) {
+			model, err := r.fileModel(path, depth+1)
+			if err == nil && model.group == parent.GroupID && model.artifact == parent.ArtifactID && model.version == parent.Version {
+				return model, nil
+			}
+		}
+	}
+	return r.artifactModel(parent.GroupID, parent.ArtifactID, parent.Version, depth+1)
+}
+
+// inheritModel merges a POM with model of its parent
+func inheritModel(project *pom, parent *pomModel) *pomModel {
+	model := &pomModel{
+		group:      project.GroupID,
+		artifact:   project.ArtifactID,
+		version:    project.Version,
+		parent:     parent.version,
+		properties: make(map[string]string),
+	}
+	if model.group == "" {
+		model.group = parent.group
+	}
+	if model.version == "" {
+		model.version = parent.version
+	}
+	for name, value := range parent.properties {
+		model.properties[name] = value
+	}
+	for name, value := range project.Properties {
+		model.properties[name] = value
+	}
+	model.managed = mergeDependencies(parent.managed, project.DependencyManagement)
+	model.dependencies = mergeDependencies(parent.dependencies, project.Dependencies)
+	return model
+}
+
+// mergeDependencies merges dependencies, child ones overriding parent ones
+func mergeDependencies(parent, child []pomDependency) []pomDependency {
+	var merged []pomDependency
+	overridden := make(map[string]bool)
+	for _, dependency := range child {
+		overridden[dependency.key()] = true
+	}
+	for _, dependency := range parent {
+		if !overridden[dependency.key()] {
+			merged = append(merged, dependency)
+		}
+	}
+	return append(merged, child...)
+}
+
+// effective interpolates model and applies dependency management
+func (r *mavenResolver) effective(model *pomModel) (*effectivePom, error) {
+	id := model.group + ":" + model.artifact + ":" + model.version
+	if effective, ok := r.effectives[id]; ok {
+		return effective, nil
+	}
+	properties := make(map[string]string)
+	for name, value := range model.properties {
+		properties[name] = value
+	}
+	for _, prefix := range []string{"project.", "pom.", ""} {
+		properties[prefix+"groupId"] = model.group
+		properties[prefix+"artifactId"] = model.artifact
+		properties[prefix+"version"] = model.version
+		properties[prefix+"parent.version"] = model.parent
+	}
+	effective := &effectivePom{managed: make(map[string]pomDependency)}
+	var imports []pomDependency
+	for _, dependency := range model.managed {
+		dependency, err := interpolateDependency(dependency, properties)
+		if err != nil {
+			return nil, err
+		}
+		if dependency.Scope == "import" && dependency.Type == "pom" {
+			imports = append(imports, dependency)
+			continue
+		}
+		effective.managed[dependency.key()] = dependency
+	}
+	// imported dependency management doesn't override local one
+	for _, bom := range imports {
+		imported, err := r.artifactModel(bom.GroupID, bom.ArtifactID, bom.Version, 0)
+		if err != nil {
+			return nil, err
+		}
+		importedEffective, err := r.effective(imported)
+		if err != nil {
+			return nil, fmt.Errorf("in imported POM '%s': %v", bom, err)
+		}
+		for key, dependency := range importedEffective.managed {
+			if _, ok := effective.managed[key]; !ok {
+				effective.managed[key] = dependency
+			}
+		}
+	}
+	for _, dependency := range model.dependencies {
+		dependency, err := interpolateDependency(dependency, properties)
+		if err != nil {
+			return nil, err
+		}
+		if management, ok := effective.managed[dependency.key()]; ok {
+			if dependency.Version == "" {
+				dependency.Version = management.Version
+			}
+			if dependency.Scope == "" {
+				dependency.Scope = management.Scope
+			}
+			if dependency.Exclusions == nil {
+				dependency.Exclusions = management.Exclusions
+			}
+		}
+		effective.dependencies = append(effective.dependencies, dependency)
+	}
+	r.effectives[id] = effective
+	return effective, nil
+}
+
+func interpolateDependency(dependency pomDependency, properties map[string]string) (pomDependency, error) {
+	var err error
+	for _, field := range []*string{&dependency.GroupID, &dependency.ArtifactID, &dependency.Version,
+		&dependency.Type, &dependency.Classifier, &dependency.Scope, &dependency.Optional} {
+		if *field, err = interpolatePom(strings.TrimSpace(*field), properties, 0); err != nil {
+			return dependency, err
+		}
+	}
+	return dependency, nil
+}
+
+// interpolatePom replaces property references in text
+func interpolatePom(text string, properties map[string]string, depth int) (string, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+	if depth > mavenMaxDepth {
+		return "", fmt.Errorf("recursive property reference in '%s'", text)
+	}
+	var err error
+	result := pomPropertyRef.ReplaceAllStringFunc(text, func(reference string) string {
+		name := reference[2 : len(reference)-1]
+		value, ok := properties[name]
+		if !ok && strings.HasPrefix(name, "env.") {
+			value, ok = os.LookupEnv(name[4:])
+		}
+		if !ok {
+			err = fmt.Errorf("unknown property '%s'", name)
+			return reference
+		}
+		value, e := interpolatePom(value, properties, depth+1)
+		if e != nil {
+			err = e
+		}
+		return value
+	})
+	return result, err
+}
@@ -0,0 +1,182 @@
+package task
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+// writeArtifact writes POM and jar of an artifact in a repository
+func writeArtifact(t *testing.T, repository, group, artifact, version, project string) {
+	dependency := pomDependency{GroupID: group, ArtifactID: artifact, Version: version}
+	pom := filepath.Join(repository, artifactPath(dependency, "pom", ""))
+	if err := os.MkdirAll(filepath.Dir(pom), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "<project><groupId>" + group + "</groupId><artifactId>" + artifact +
+		"</artifactId><version>" + version + "</version>" + project + "</project>"
+	if err := os.WriteFile(pom, []byte(content), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	jar := filepath.Join(repository, artifactPath(dependency, "jar", ""))
+	if err := os.WriteFile(jar, []byte(artifact), FileMode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dependencyXML(group, artifact, version, extra string) string {
+	return "<dependency><groupId>" + group + "</groupId><artifactId>" + artifact +
+		"</artifactId><version>" + version + "</version>" + extra + "</dependency>"
+}
+
+func TestMavenResolution(t *testing.T) {
+	repository := t.TempDir()
+	local := t.TempDir()
+	// parent with properties and dependency management, importing a BOM
+	writeArtifact(t, repository, "org.bom", "bom", "1.0", "<packaging>pom</packaging>"+
+		"<dependencyManagement><dependencies>"+dependencyXML("org.d", "d", "2.0", "")+
+		"</dependencies></dependencyManagement>")
+	writeArtifact(t, repository, "org.parent", "parent", "1.0", "<packaging>pom</packaging>"+
+		"<properties><b.version>1.${minor}</b.version><minor>5</minor></properties>"+
+		"<dependencyManagement><dependencies>"+
+		dependencyXML("org.b", "b", "${b.version}", "")+
+		dependencyXML("org.bom", "bom", "1.0", "<type>pom</type><scope>import</scope>")+
+		"</dependencies></dependencyManagement>")
+	// a depends on b (managed version), d (from BOM), optional e and test f
+	writeArtifact(t, repository, "org.a", "a", "1.0",
+		"<parent><groupId>org.parent</groupId><artifactId>parent</artifactId><version>1.0</version></parent>"+
+			"<dependencies>"+
+			dependencyXML("org.b", "b", "", "<exclusions><exclusion><groupId>org.x</groupId><artifactId>*</artifactId></exclusion></exclusions>")+
+			dependencyXML("org.d", "d", "", "")+
+			dependencyXML("org.e", "e", "1.0", "<optional>true</optional>")+
+			dependencyXML("org.f", "f", "1.0", "<scope>test</scope>")+
+			"</dependencies>")
+	// b depends on excluded x, runtime c and d in another version
+	writeArtifact(t, repository, "org.b", "b", "1.5", "<dependencies>"+
+		dependencyXML("org.x", "x", "1.0", "")+
+		dependencyXML("org.c", "c", "${project.version}", "<scope>runtime</scope>")+
+		dependencyXML("org.d", "d", "1.0", "")+
+		"</dependencies>")
+	writeArtifact(t, repository, "org.c", "c", "1.5", "")
+	writeArtifact(t, repository, "org.x", "x", "1.0", "")
+	writeArtifact(t, repository, "org.d", "d", "1.0", "")
+	writeArtifact(t, repository, "org.d", "d", "2.0", "")
+	resolver := newMavenResolver(build.NewContext(nil), local, []string{"file://" + repository})
+	root, err := parseArtifact("org.a:a:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := resolver.resolve([]pomDependency{root}, nil, nil)
+	if err != nil {
+		t.Fatalf("resolving dependencies: %v", err)
+	}
+	expected := []string{"a-1.0.jar", "b-1.5.jar", "d-2.0.jar"}
+	if names := jarNames(paths, local, t); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("bad dependencies: %v", names)
+	}
+	paths, err = resolver.resolve([]pomDependency{root}, nil, []string{"runtime"})
+	if err != nil {
+		t.Fatalf("resolving dependencies: %v", err)
+	}
+	expected = []string{"a-1.0.jar", "b-1.5.jar", "d-2.0.jar", "c-1.5.jar"}
+	if names := jarNames(paths, local, t); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("bad runtime dependencies: %v", names)
+	}
+	// project POM with parent in relative path, dependency management
+	// overriding versions of transitive dependencies
+	project := t.TempDir()
+	parent := "<project><groupId>org.project</groupId><artifactId>parent</artifactId><version>2.0</version>" +
+		"<dependencyManagement><dependencies>" + dependencyXML("org.d", "d", "1.0", "") +
+		"</dependencies></dependencyManagement></project>"
+	if err := os.WriteFile(filepath.Join(project, "pom.xml"), []byte(parent), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	module := "<project><parent><groupId>org.project</groupId><artifactId>parent</artifactId><version>2.0</version></parent>" +
+		"<artifactId>module</artifactId><dependencies>" + dependencyXML("org.b", "b", "1.5", "") +
+		"</dependencies></project>"
+	if err := os.MkdirAll(filepath.Join(project, "module"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(project, "module", "pom.xml")
+	if err := os.WriteFile(file, []byte(module), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	roots, managed, err := resolver.projectDependencies(file)
+	if err != nil {
+		t.Fatalf("reading project dependencies: %v", err)
+	}
+	paths, err = resolver.resolve(roots, managed, nil)
+	if err != nil {
+		t.Fatalf("resolving dependencies: %v", err)
+	}
+	expected = []string{"b-1.5.jar", "x-1.0.jar", "d-1.0.jar"}
+	if names := jarNames(paths, local, t); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("bad project dependencies: %v", names)
+	}
+}
+
+// jarNames returns names of jar files, checking they are in local repository
+func jarNames(paths []string, local string, t *testing.T) []string {
+	var names []string
+	for _, path := range paths {
+		if !strings.HasPrefix(path, local) {
+			t.Errorf("jar file '%s' not in local repository", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("jar file '%s' not found", path)
+		}
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+func TestPomVersion(t *testing.T) {
+	version, err := pomVersion(pomDependency{Version: "[1.2.3]"})
+	if err != nil || version != "1.2.3" {
+		t.Errorf("bad fixed version: %s, %v", version, err)
+	}
+	if _, err := pomVersion(pomDependency{Version: "[1.0,2.0)"}); err == nil {
+		t.Errorf("version ranges should not be supported")
+	}
+	if _, err := pomVersion(pomDependency{}); err == nil {
+		t.Errorf("missing version should fail")
+	}
+}
+
+func TestMavenTruncatedDownload(t *testing.T) {
+	truncate := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if truncate {
+			// connection is closed before announced length is sent
+			w.Header().Set("Content-Length", "100")
+			_, _ = w.Write([]byte("truncated"))
+			return
+		}
+		_, _ = w.Write([]byte("complete"))
+	}))
+	defer server.Close()
+	local := t.TempDir()
+	resolver := newMavenResolver(build.NewContext(nil), local, []string{server.URL})
+	path := "org/a/a/1.0/a-1.0.jar"
+	if _, err := resolver.fetch(path); err == nil {
+		t.Fatalf("truncated download should fail")
+	}
+	files, _ := filepath.Glob(filepath.Join(local, "org", "a", "a", "1.0", "*"))
+	if len(files) > 0 {
+		t.Errorf("truncated download should leave no file: %v", files)
+	}
+	truncate = false
+	file, err := resolver.fetch(path)
+	if err != nil {
+		t.Fatalf("downloading file: %v", err)
+	}
+	content, _ := os.ReadFile(file)
+	if string(content) != "complete" {
+		t.Errorf("bad downloaded content: %s", content)
+	}
+}
@@ -22,6 +22,7 @@ Arguments:
 - noreturn: if set to true, do not print a newline at the end (bool, optional).
 
 Possible colors are black, red, green, yellow, blue, magenta, cyan and white.
+Values of secrets are replaced with '********'.
 
 Examples:
 
@@ -53,6 +54,7 @@ var Colors = map[string]color.Attribute{
 
 func print(context *build.Context, args interface{}) error {
 	params := args.(printArgs)
+	params.Print = build.Mask(params.Print)
 	if params.Color != "" {
 		colorPrint, ok := Colors[params.Color]
 		if !ok {
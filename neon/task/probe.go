@@ -0,0 +1,165 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	t "time"
+
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+)
+
+const (
+	// DefaultProbeTimeout is the default time to wait for a probe, in seconds
+	DefaultProbeTimeout = 30.0
+	// DefaultProbeInterval is the default delay between two probe attempts, in
+	// seconds
+	DefaultProbeInterval = 0.2
+	// probeConnectTimeout is the timeout to connect to a port
+	probeConnectTimeout = 200 * t.Millisecond
+)
+
+// probe tells if a condition is met
+type probe struct {
+	description string
+	ready       func() bool
+}
+
+// portProbe checks that a TCP port accepts connections
+func portProbe(host string, port int) probe {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	return probe{
+		description: "port " + address,
+		ready: func() bool {
+			connection, err := net.DialTimeout("tcp", address, probeConnectTimeout)
+			if err != nil {
+				return false
+			}
+			_ = connection.Close()
+			return true
+		},
+	}
+}
+
+// urlProbe checks that an HTTP URL responds with expected status:
+// - url: the URL to request
+// - method: the request method, defaults to GET
+// - headers: the request headers
+// - status: expected status, any status lower than 400 if 0
+func urlProbe(url, method string, headers map[string]string, status int) probe {
+	client := &http.Client{Timeout: t.Second}
+	if method == "" {
+		method = DefaultMethod
+	}
+	return probe{
+		description: "URL " + url,
+		ready: func() bool {
+			request, err := http.NewRequest(method, url, nil)
+			if err != nil {
+				return false
+			}
+			for name, value := range headers {
+				request.Header.Set(name, value)
+			}
+			response, err := client.Do(request)
+			if err != nil {
+				return false
+			}
+			_ = response.Body.Close()
+			if status == 0 {
+				return response.StatusCode < 400
+			}
+			return response.StatusCode == status
+		},
+	}
+}
+
+// fileProbe checks that a file exists
+func fileProbe(file string) probe {
+	return probe{
+		description: "file " + file,
+		ready: func() bool {
+			return util.FileExists(file) || util.DirExists(file)
+		},
+	}
+}
+
+// commandProbe checks that a command succeeds, command output is discarded
+func commandProbe(context *build.Context, command []string) probe {
+	return probe{
+		description: "command " + strings.Join(command, " "),
+		ready: func() bool {
+			return run(command, nil, io.Discard, io.Discard, nil, context, nil, false) == nil
+		},
+	}
+}
+
+// logWriter is a writer that looks for a regexp in lines written into it
+type logWriter struct {
+	mutex   sync.Mutex
+	pattern *regexp.Regexp
+	line    []byte
+	found   bool
+}
+
+func (writer *logWriter) Write(data []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	for _, b := range data {
+		if b == '\n' {
+			if writer.pattern.Match(writer.line) {
+				writer.found = true
+			}
+			writer.line = writer.line[:0]
+		} else {
+			writer.line = append(writer.line, b)
+		}
+	}
+	// match incomplete line, such as a prompt
+	if writer.pattern.Match(writer.line) {
+		writer.found = true
+	}
+	return len(data), nil
+}
+
+// logProbe checks that a line matching writer pattern was written
+func logProbe(writer *logWriter) probe {
+	return probe{
+		description: "log matching '" + writer.pattern.String() + "'",
+		ready: func() bool {
+			writer.mutex.Lock()
+			defer writer.mutex.Unlock()
+			return writer.found
+		},
+	}
+}
+
+// waitProbes waits until all probes are ready:
+// - probes: the probes to wait for
+// - timeout: maximum time to wait, in seconds
+// - interval: delay between two attempts, in seconds
+// - failed: function that returns an error if we should stop waiting
+// Return: an error on timeout or failure
+func waitProbes(probes []probe, timeout, interval float64, failed func() error) error {
+	deadline := t.Now().Add(t.Duration(timeout * float64(t.Second)))
+	for _, p := range probes {
+		for !p.ready() {
+			if failed != nil {
+				if err := failed(); err != nil {
+					return err
+				}
+			}
+			if t.Now().After(deadline) {
+				return fmt.Errorf("timeout waiting for %s", p.description)
+			}
+			t.Sleep(t.Duration(interval * float64(t.Second)))
+		}
+	}
+	return nil
+}
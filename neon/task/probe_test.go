@@ -0,0 +1,45 @@
+package task
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	if !urlProbe(server.URL, "", nil, 0).ready() {
+		t.Errorf("URL probe should be ready")
+	}
+	if urlProbe(server.URL+"/missing", "", nil, 0).ready() {
+		t.Errorf("URL probe should not be ready on error status")
+	}
+	if !urlProbe(server.URL+"/missing", "HEAD", nil, 404).ready() {
+		t.Errorf("URL probe should be ready with expected status")
+	}
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+	if !portProbe("127.0.0.1", port).ready() {
+		t.Errorf("port probe should be ready")
+	}
+	writer := &logWriter{pattern: regexp.MustCompile(`^ready on \d+$`)}
+	log := logProbe(writer)
+	_, _ = writer.Write([]byte("starting\nready on "))
+	if log.ready() {
+		t.Errorf("log probe should not be ready")
+	}
+	_, _ = writer.Write([]byte("8080\n"))
+	if !log.ready() {
+		t.Errorf("log probe should be ready")
+	}
+	err := waitProbes([]probe{urlProbe(server.URL+"/missing", "", nil, 0)}, 0.3, 0.1, nil)
+	if err == nil || err.Error() != "timeout waiting for URL "+server.URL+"/missing" {
+		t.Errorf("bad timeout error: %v", err)
+	}
+}
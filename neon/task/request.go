@@ -2,11 +2,19 @@ package task
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	t "time"
 
 	"github.com/c4s4/neon/neon/build"
 )
@@ -16,6 +24,8 @@ const (
 	DefaultMethod = "GET"
 	// DefaultStatus is the default expected response status
 	DefaultStatus = 200
+	// DefaultRetryDelay is the default delay before first retry, in seconds
+	DefaultRetryDelay = 1.0
 )
 
 func init() {
@@ -33,21 +43,64 @@ Arguments:
 - headers: request headers (map with string keys and values, optional).
 - body: request body (string, optional).
 - file: request body as a file (string, optional, file).
+- form: fields of a multipart form to send (map with string keys and values,
+  optional).
+- files: files of a multipart form to send, by field name (map with string
+  keys and values, optional).
 - username: user name for authentication (string, optional).
 - password: user password for authentication (string, optional).
+- token: bearer token for authentication (string, optional).
+- cert: client certificate file in PEM format (string, optional, file).
+- key: client key file in PEM format (string, optional, file).
+- ca: file of certificate authorities in PEM format to verify server
+  certificate (string, optional, file).
 - status: expected status code, raise an error if different, defaults to 200
   (int, optional).
+- tofile: file to write response body into (string, optional, file).
+- resume: resume download of a partial file set with tofile (boolean,
+  optional).
+- json: name of the property to set with decoded JSON response body (string,
+  optional).
+- retries: number of retries on network errors and server errors (status 5xx
+  or 429), defaults to 0 (int, optional).
+- delay: delay before first retry in seconds, doubled after each retry,
+  defaults to 1.0 (float, optional).
+- timeout: timeout of the request in seconds, no timeout if not set (float,
+  optional).
 
 Examples:
 
     # get google.com
     - request: 'google.com'
+    # download a file, resuming partial download and retrying on errors
+    - request: 'https://example.com/archive.tar.gz'
+      tofile:  'build/archive.tar.gz'
+      resume:  true
+      retries: 3
+    # upload an artifact with a multipart form and bearer token
+    - request: 'https://repo.example.com/upload'
+      method:  'POST'
+      token:   '={REPO_TOKEN}'
+      form:
+        version: '={VERSION}'
+      files:
+        artifact: 'build/app.tar.gz'
+      status:  201
+    # get JSON response in property 'release'
+    - request: 'https://api.example.com/release/latest'
+      json:    'release'
+    - print: 'Latest version: ={release["version"]}'
 
 Notes:
 
 - Response status code is stored in variable _status.
-- Response body is stored in variable _body.
-- Response headers are stored in variable _headers.`,
+- Response body is stored in variable _body, unless written in a file with
+  tofile.
+- Response headers are stored in variable _headers.
+- Only one of body, file and multipart form (form and files) may be set.
+- When resuming a download, a range is requested from the size of the existing
+  file. A partial content response (status 206) is then accepted for expected
+  status 200.`,
 	})
 }
 
@@ -57,14 +110,25 @@ type requestArgs struct {
 	Headers  map[string]string `neon:"optional"`
 	Body     string            `neon:"optional"`
 	File     string            `neon:"optional,file"`
+	Form     map[string]string `neon:"optional"`
+	Files    map[string]string `neon:"optional"`
 	Username string            `neon:"optional"`
 	Password string            `neon:"optional"`
+	Token    string            `neon:"optional"`
+	Cert     string            `neon:"optional,file"`
+	Key      string            `neon:"optional,file"`
+	Ca       string            `neon:"optional,file"`
 	Status   int               `neon:"optional"`
+	Tofile   string            `neon:"optional,file"`
+	Resume   bool              `neon:"optional"`
+	JSON     string            `neon:"optional"`
+	Retries  int               `neon:"optional"`
+	Delay    float64           `neon:"optional"`
+	Timeout  float64           `neon:"optional"`
 }
 
 func request(context *build.Context, args interface{}) error {
 	params := args.(requestArgs)
-	var err error
 	method := params.Method
 	if method == "" {
 		method = DefaultMethod
@@ -73,16 +137,154 @@ func request(context *build.Context, args interface{}) error {
 	if status == 0 {
 		status = DefaultStatus
 	}
-	body := []byte(params.Body)
+	bodies := 0
+	for _, set := range []bool{params.Body != "", params.File != "", params.Form != nil || params.Files != nil} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return fmt.Errorf("only one of body, file and multipart form may be set")
+	}
+	if params.Resume && params.Tofile == "" {
+		return fmt.Errorf("resume requires tofile")
+	}
+	client, err := requestClient(params)
+	if err != nil {
+		return err
+	}
+	delay := params.Delay
+	if delay == 0 {
+		delay = DefaultRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := doRequest(context, client, method, status, params)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= params.Retries {
+			return err
+		}
+		context.MessageArgs("Retrying request in %gs: %v", delay, err)
+		t.Sleep(t.Duration(delay * float64(t.Second)))
+		delay *= 2
+	}
+}
+
+// requestClient builds the HTTP client with TLS configuration
+func requestClient(params requestArgs) (*http.Client, error) {
+	client := &http.Client{}
+	if params.Timeout > 0 {
+		client.Timeout = t.Duration(params.Timeout * float64(t.Second))
+	}
+	if params.Cert == "" && params.Key == "" && params.Ca == "" {
+		return client, nil
+	}
+	config := &tls.Config{}
+	if params.Cert != "" || params.Key != "" {
+		if params.Cert == "" || params.Key == "" {
+			return nil, fmt.Errorf("client certificate requires both cert and key")
+		}
+		certificate, err := tls.LoadX509KeyPair(params.Cert, params.Key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if params.Ca != "" {
+		pem, err := os.ReadFile(params.Ca)
+		if err != nil {
+			return nil, fmt.Errorf("reading certificate authorities: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in '%s'", params.Ca)
+		}
+		config.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	client.Transport = transport
+	return client, nil
+}
+
+// requestBody returns the body of the request and its content type
+func requestBody(params requestArgs) (io.ReadCloser, int64, string, error) {
 	if params.File != "" {
-		body, err = os.ReadFile(params.File)
+		file, err := os.Open(params.File)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return nil, 0, "", err
+		}
+		return file, info.Size(), "", nil
+	}
+	if params.Form != nil || params.Files != nil {
+		reader, writer := io.Pipe()
+		form := multipart.NewWriter(writer)
+		go func() {
+			_ = writer.CloseWithError(writeMultipart(form, params.Form, params.Files))
+		}()
+		return reader, -1, form.FormDataContentType(), nil
+	}
+	if params.Body == "" {
+		return http.NoBody, 0, "", nil
+	}
+	return io.NopCloser(bytes.NewBufferString(params.Body)), int64(len(params.Body)), "", nil
+}
+
+func writeMultipart(form *multipart.Writer, fields, files map[string]string) error {
+	// fields are written in order of names
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := form.WriteField(name, fields[name]); err != nil {
+			return err
+		}
+	}
+	names = nil
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		part, err := form.CreateFormFile(name, filepath.Base(files[name]))
 		if err != nil {
 			return err
 		}
+		file, err := os.Open(files[name])
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, file)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return form.Close()
+}
+
+// doRequest performs a request and tells if it may be retried on error
+func doRequest(context *build.Context, client *http.Client, method string, status int, params requestArgs) (bool, error) {
+	body, length, contentType, err := requestBody(params)
+	if err != nil {
+		return false, fmt.Errorf("building request body: %v", err)
 	}
-	request, err := http.NewRequest(method, params.Request, bytes.NewBuffer([]byte(body)))
+	request, err := http.NewRequest(method, params.Request, body)
 	if err != nil {
-		return fmt.Errorf("building request: %v", err)
+		_ = body.Close()
+		return false, fmt.Errorf("building request: %v", err)
+	}
+	request.ContentLength = length
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
 	}
 	for name, value := range params.Headers {
 		request.Header.Set(name, value)
@@ -90,23 +292,66 @@ func request(context *build.Context, args interface{}) error {
 	if params.Username != "" {
 		request.SetBasicAuth(params.Username, params.Password)
 	}
-	client := &http.Client{}
+	if params.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+params.Token)
+	}
+	var offset int64
+	if params.Resume {
+		if info, err := os.Stat(params.Tofile); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		}
+	}
 	response, err := client.Do(request)
 	if err != nil {
-		return fmt.Errorf("requesting '%s': %v", params.Request, err)
+		return true, fmt.Errorf("requesting '%s': %v", params.Request, err)
 	}
 	defer func() {
 		_ = response.Body.Close()
 	}()
 	context.SetProperty("_status", response.StatusCode)
 	context.SetProperty("_headers", response.Header)
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	if offset > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// file was already fully downloaded
+		context.SetProperty("_status", status)
+		return false, nil
+	}
+	if response.StatusCode != status &&
+		!(offset > 0 && status == http.StatusOK && response.StatusCode == http.StatusPartialContent) {
+		responseBody, _ := io.ReadAll(response.Body)
+		context.SetProperty("_body", string(responseBody))
+		return retry, fmt.Errorf("bad response status: %d", response.StatusCode)
+	}
+	if params.Tofile != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if response.StatusCode == http.StatusPartialContent {
+			flags = os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(params.Tofile, flags, FileMode)
+		if err != nil {
+			return false, fmt.Errorf("opening destination file: %v", err)
+		}
+		_, err = io.Copy(file, response.Body)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return true, fmt.Errorf("downloading to '%s': %v", params.Tofile, err)
+		}
+		return false, nil
+	}
 	responseBody, err := io.ReadAll(response.Body)
 	if err != nil {
-		return fmt.Errorf("reading response body: %v", err)
+		return true, fmt.Errorf("reading response body: %v", err)
 	}
 	context.SetProperty("_body", string(responseBody))
-	if response.StatusCode != status {
-		return fmt.Errorf("bad response status: %d", response.StatusCode)
+	if params.JSON != "" {
+		var value interface{}
+		if err := json.Unmarshal(responseBody, &value); err != nil {
+			return false, fmt.Errorf("decoding JSON response: %v", err)
+		}
+		context.SetProperty(params.JSON, value)
 	}
-	return nil
+	return false, nil
 }
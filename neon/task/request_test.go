@@ -0,0 +1,143 @@
+package task
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	tm "time"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+const downloadContent = "0123456789"
+
+func TestRequestDownloadResume(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", tm.Time{}, strings.NewReader(downloadContent))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte(downloadContent[:4]), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	context := build.NewContext(nil)
+	if err := request(context, requestArgs{Request: server.URL, Tofile: file, Resume: true}); err != nil {
+		t.Fatalf("downloading file: %v", err)
+	}
+	downloaded, _ := os.ReadFile(file)
+	if string(downloaded) != downloadContent {
+		t.Errorf("bad downloaded content: %s", downloaded)
+	}
+	status, _ := context.GetProperty("_status")
+	if status != http.StatusPartialContent {
+		t.Errorf("bad status: %v", status)
+	}
+	// resuming a complete download does nothing
+	if err := request(context, requestArgs{Request: server.URL, Tofile: file, Resume: true}); err != nil {
+		t.Fatalf("resuming complete download: %v", err)
+	}
+	downloaded, _ = os.ReadFile(file)
+	if string(downloaded) != downloadContent {
+		t.Errorf("bad downloaded content: %s", downloaded)
+	}
+}
+
+func TestRequestMultipartToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		field := r.FormValue("version")
+		file, header, err := r.FormFile("artifact")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"version": "%s", "name": "%s", "content": "%s"}`, field, header.Filename, data)
+	}))
+	defer server.Close()
+	artifact := filepath.Join(t.TempDir(), "artifact.txt")
+	if err := os.WriteFile(artifact, []byte("artifact"), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	context := build.NewContext(nil)
+	err := request(context, requestArgs{
+		Request: server.URL,
+		Method:  "POST",
+		Token:   "secret",
+		Form:    map[string]string{"version": "1.2.3"},
+		Files:   map[string]string{"artifact": artifact},
+		Status:  http.StatusCreated,
+		JSON:    "response",
+	})
+	if err != nil {
+		t.Fatalf("uploading file: %v", err)
+	}
+	value, _ := context.GetProperty("response")
+	response, ok := value.(map[string]interface{})
+	if !ok || response["version"] != "1.2.3" || response["name"] != "artifact.txt" || response["content"] != "artifact" {
+		t.Errorf("bad response: %v", value)
+	}
+	err = request(context, requestArgs{Request: server.URL, Method: "POST", Token: "bad"})
+	if err == nil || err.Error() != "bad response status: 401" {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestRequestRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+	context := build.NewContext(nil)
+	if err := request(context, requestArgs{Request: server.URL, Retries: 1, Delay: 0.01}); err == nil {
+		t.Errorf("request should fail after one retry")
+	}
+	calls = 0
+	if err := request(context, requestArgs{Request: server.URL, Retries: 2, Delay: 0.01}); err != nil {
+		t.Fatalf("request should succeed after two retries: %v", err)
+	}
+	body, _ := context.GetProperty("_body")
+	if body != "OK" {
+		t.Errorf("bad body: %v", body)
+	}
+}
+
+func TestRequestCertificateAuthority(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secure"))
+	}))
+	defer server.Close()
+	context := build.NewContext(nil)
+	if err := request(context, requestArgs{Request: server.URL}); err == nil {
+		t.Errorf("request should fail with unknown authority")
+	}
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(ca, certificate, FileMode); err != nil {
+		t.Fatal(err)
+	}
+	if err := request(context, requestArgs{Request: server.URL, Ca: ca}); err != nil {
+		t.Fatalf("request with certificate authority: %v", err)
+	}
+	body, _ := context.GetProperty("_body")
+	if body != "secure" {
+		t.Errorf("bad body: %v", body)
+	}
+}
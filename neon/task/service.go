@@ -0,0 +1,187 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"syscall"
+	t "time"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+// serviceStopTimeout is the time given to a service to stop before killing it
+var serviceStopTimeout = 5 * t.Second
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "service",
+		Func: service,
+		Args: reflect.TypeOf(serviceArgs{}),
+		Help: `Start a service in background, wait until it is ready and stop it when
+target ends.
+
+Arguments:
+
+- service: command to run (strings).
+- port: wait until this TCP port accepts connections (int, optional).
+- host: host of the port, defaults to 'localhost' (string, optional).
+- url: wait until this URL responds with a status lower than 400 (string,
+  optional).
+- log: wait until service prints a line matching this regexp (string,
+  optional).
+- timeout: time to wait for service to be ready, in seconds, defaults to 30
+  (float, optional).
+- pid: name of the variable to put PID into (string, optional).
+- build: stop service when build ends instead of current target (boolean,
+  optional).
+
+Examples:
+
+    # start a database and wait for its port
+    - service: ['docker', 'run', '--rm', '-p', '5432:5432', 'postgres']
+      port:    5432
+    # start a web server for the whole build and wait for its URL
+    - service: ['python3', '-m', 'http.server', '8000']
+      url:     'http://localhost:8000/'
+      build:   true
+    # start a server and wait for a log line
+    - service: ['./server']
+      log:     'Listening on'
+      timeout: 10.0
+
+Notes:
+
+- Service is stopped when target (or build) ends, even on failure. It is first
+  sent a termination signal, and killed if it is still running after 5
+  seconds.
+- Task fails if service exits before being ready.
+- Only the service process receives the termination signal, thus a service
+  started with a shell should run its command with exec, as in
+  ['sh', '-c', 'exec ./server'].
+- Output of the service is printed on the console. Once service has exited,
+  output of processes it started is not waited for more than 5 seconds.`,
+	})
+}
+
+type serviceArgs struct {
+	Service []string `neon:"string"`
+	Port    int      `neon:"optional"`
+	Host    string   `neon:"optional"`
+	Url     string   `neon:"optional"`
+	Log     string   `neon:"optional"`
+	Timeout float64  `neon:"optional"`
+	Pid     string   `neon:"optional"`
+	Build   bool     `neon:"optional"`
+}
+
+func service(context *build.Context, args interface{}) error {
+	params := args.(serviceArgs)
+	if len(params.Service) == 0 {
+		return fmt.Errorf("service command is empty")
+	}
+	var probes []probe
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if params.Log != "" {
+		pattern, err := regexp.Compile(params.Log)
+		if err != nil {
+			return fmt.Errorf("compiling log regexp: %v", err)
+		}
+		writer := &logWriter{pattern: pattern}
+		stdout = io.MultiWriter(stdout, writer)
+		stderr = io.MultiWriter(stderr, writer)
+		probes = append(probes, logProbe(writer))
+	}
+	if params.Port != 0 {
+		host := params.Host
+		if host == "" {
+			host = "localhost"
+		}
+		probes = append(probes, portProbe(host, params.Port))
+	}
+	if params.Url != "" {
+		probes = append(probes, urlProbe(params.Url, "", nil, 0))
+	}
+	timeout := params.Timeout
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	name := strings.Join(params.Service, " ")
+	cmd := exec.Command(params.Service[0], params.Service[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// output is copied through pipes that processes started by the service
+	// may keep open after it exited
+	cmd.WaitDelay = serviceStopTimeout
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting service: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	context.AddFinalizer(params.Build, func() error {
+		return stopService(context, name, cmd, done)
+	})
+	if params.Pid != "" {
+		context.SetProperty(params.Pid, cmd.Process.Pid)
+	}
+	context.MessageArgs("Service '%s' started with PID %d", name, cmd.Process.Pid)
+	exited := func() error {
+		select {
+		case err := <-done:
+			// put result back for finalizer
+			done <- err
+			if err != nil {
+				return fmt.Errorf("service exited before being ready: %v", err)
+			}
+			return fmt.Errorf("service exited before being ready")
+		default:
+			return nil
+		}
+	}
+	if err := waitProbes(probes, timeout, DefaultProbeInterval, exited); err != nil {
+		return err
+	}
+	if len(probes) > 0 {
+		context.MessageArgs("Service '%s' is ready", name)
+	}
+	return nil
+}
+
+// stopService terminates a service and waits for it to exit, killing it if it
+// doesn't stop in time
+func stopService(context *build.Context, name string, cmd *exec.Cmd, done chan error) error {
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+	context.MessageArgs("Stopping service '%s'", name)
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return killService(cmd, done)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-t.After(serviceStopTimeout):
+		return killService(cmd, done)
+	}
+}
+
+func killService(cmd *exec.Cmd, done chan error) error {
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("killing service: %v", err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-t.After(2 * serviceStopTimeout):
+		return fmt.Errorf("service didn't exit after being killed")
+	}
+}
@@ -0,0 +1,33 @@
+package task
+
+import (
+	"testing"
+	tm "time"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+func TestServiceStopWithLog(t *testing.T) {
+	timeout := serviceStopTimeout
+	serviceStopTimeout = 100 * tm.Millisecond
+	defer func() {
+		serviceStopTimeout = timeout
+	}()
+	context := build.NewContext(nil)
+	// service ignores termination signal and its child keeps output open
+	args := serviceArgs{
+		Service: []string{"sh", "-c", "trap '' TERM; echo ready; sleep 30; echo done"},
+		Log:     "ready",
+		Build:   true,
+	}
+	if err := service(context, args); err != nil {
+		t.Fatalf("starting service: %v", err)
+	}
+	start := tm.Now()
+	if err := context.RunFinalizers(nil); err != nil {
+		t.Errorf("stopping service: %v", err)
+	}
+	if duration := tm.Since(start); duration > 10*tm.Second {
+		t.Errorf("stopping service took %v", duration)
+	}
+}
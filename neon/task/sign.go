@@ -0,0 +1,180 @@
+package task
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// DefaultSignatureSuffix is the default suffix of signature files
+const DefaultSignatureSuffix = ".sig"
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "sign",
+		Func: sign,
+		Args: reflect.TypeOf(signArgs{}),
+		Help: `Write or verify detached ed25519 signatures of files.
+
+Arguments:
+
+- sign: globs of files to sign (strings, file, wrap).
+- dir: root directory for globs, defaults to '.' (string, optional, file).
+- exclude: globs of files to exclude (strings, optional, file, wrap).
+- key: PEM file of the key (string, file).
+- verify: verify signatures instead of writing them (boolean, optional).
+- suffix: suffix of signature files, defaults to '.sig' (string, optional).
+
+Examples:
+
+    # sign archives in build directory with private key
+    - sign: '*.tar.gz'
+      dir:  'build'
+      key:  '~/.neon/private.pem'
+    # verify signatures of archives in build directory with public key
+    - sign:   '*.tar.gz'
+      dir:    'build'
+      key:    'public.pem'
+      verify: true
+
+Notes:
+
+- Signature of a file is written in a file with same name and signature suffix
+  (such as 'foo.tar.gz.sig' for file 'foo.tar.gz'). This is the raw 64 bytes
+  ed25519 signature.
+- Signing requires a private key in PKCS #8 PEM format. Verifying accepts a
+  public key in PKIX PEM format or a private key. Such keys may be generated
+  with commands 'openssl genpkey -algorithm ed25519 -out private.pem' and
+  'openssl pkey -in private.pem -pubout -out public.pem'.
+- Signatures may be verified with openssl with command 'openssl pkeyutl -verify
+  -pubin -inkey public.pem -rawin -in foo.tar.gz -sigfile foo.tar.gz.sig'.`,
+	})
+}
+
+type signArgs struct {
+	Sign    []string `neon:"file,wrap"`
+	Dir     string   `neon:"optional,file"`
+	Exclude []string `neon:"optional,file,wrap"`
+	Key     string   `neon:"file"`
+	Verify  bool     `neon:"optional"`
+	Suffix  string   `neon:"optional"`
+}
+
+func sign(context *build.Context, args interface{}) error {
+	params := args.(signArgs)
+	suffix := params.Suffix
+	if suffix == "" {
+		suffix = DefaultSignatureSuffix
+	}
+	files, err := util.FindFiles(params.Dir, params.Sign, params.Exclude, false)
+	if err != nil {
+		return fmt.Errorf("getting source files for sign task: %v", err)
+	}
+	if params.Verify {
+		publicKey, err := readPublicKey(params.Key)
+		if err != nil {
+			return err
+		}
+		context.MessageArgs("Verifying signatures of %d file(s)", len(files))
+		var failed []string
+		for _, file := range files {
+			path := filepath.Join(params.Dir, file)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading file to verify: %v", err)
+			}
+			signature, err := os.ReadFile(path + suffix)
+			if err != nil {
+				return fmt.Errorf("reading signature: %v", err)
+			}
+			if !ed25519.Verify(publicKey, data, signature) {
+				failed = append(failed, file)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("signature verification failed for %s", strings.Join(failed, ", "))
+		}
+		return nil
+	}
+	privateKey, err := readPrivateKey(params.Key)
+	if err != nil {
+		return err
+	}
+	context.MessageArgs("Signing %d file(s)", len(files))
+	for _, file := range files {
+		path := filepath.Join(params.Dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading file to sign: %v", err)
+		}
+		signature := ed25519.Sign(privateKey, data)
+		if err := os.WriteFile(path+suffix, signature, FileMode); err != nil {
+			return fmt.Errorf("writing signature: %v", err)
+		}
+	}
+	return nil
+}
+
+func readKey(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in key file '%s'", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (ed25519.PrivateKey, error) {
+	block, err := readKey(file)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("key file '%s' is not a PKCS #8 private key", file)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %v", err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key in file '%s' is not an ed25519 key", file)
+	}
+	return privateKey, nil
+}
+
+func readPublicKey(file string) (ed25519.PublicKey, error) {
+	block, err := readKey(file)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		privateKey, err := readPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey.Public().(ed25519.PublicKey), nil
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("key file '%s' is not a PKIX public key", file)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %v", err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key in file '%s' is not an ed25519 key", file)
+	}
+	return publicKey, nil
+}
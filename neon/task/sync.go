@@ -0,0 +1,224 @@
+package task
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	t "time"
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "sync",
+		Func: syncFunc,
+		Args: reflect.TypeOf(syncArgs{}),
+		Help: `Synchronize files of a directory to another one.
+
+Arguments:
+
+- sync: globs of files to synchronize (strings, file, wrap).
+- dir: root directory for globs, defaults to '.' (string, optional, file).
+- exclude: globs of files to exclude (strings, optional, file, wrap).
+- todir: destination directory (string, file).
+- compare: how to tell that a file changed, 'time' to compare size and
+  modification time or 'hash' to compare contents, defaults to 'time'
+  (string, optional).
+- prune: delete files in destination directory that are not in source
+  (boolean, optional).
+- dryrun: print files that would be copied or deleted without doing it
+  (boolean, optional).
+
+Examples:
+
+    # mirror assets directory to build/assets
+    - sync:  '**/*'
+      dir:   'assets'
+      todir: 'build/assets'
+      prune: true
+    # print files that would be synchronized
+    - sync:   '**/*'
+      dir:    'assets'
+      todir:  'build/assets'
+      dryrun: true
+
+Notes:
+
+- Only files that changed are copied. File modes and modification times are
+  copied too.
+- When pruning, only files of destination directory that are selected by
+  globs, and are not excluded, are deleted. Directories left empty are
+  deleted too.`,
+	})
+}
+
+type syncArgs struct {
+	Sync    []string `neon:"file,wrap"`
+	Dir     string   `neon:"optional,file"`
+	Exclude []string `neon:"optional,file,wrap"`
+	Todir   string   `neon:"file"`
+	Compare string   `neon:"optional"`
+	Prune   bool     `neon:"optional"`
+	Dryrun  bool     `neon:"optional"`
+}
+
+func syncFunc(context *build.Context, args interface{}) error {
+	params := args.(syncArgs)
+	compare := params.Compare
+	if compare == "" {
+		compare = "time"
+	}
+	if compare != "time" && compare != "hash" {
+		return fmt.Errorf("unknown comparison '%s' (must be 'time' or 'hash')", compare)
+	}
+	sources, err := util.FindFiles(params.Dir, params.Sync, params.Exclude, false)
+	if err != nil {
+		return fmt.Errorf("getting source files for sync task: %v", err)
+	}
+	var copied, deleted []string
+	unchanged := 0
+	for _, file := range sources {
+		source := filepath.Join(params.Dir, file)
+		dest := filepath.Join(params.Todir, file)
+		changed, err := fileChanged(source, dest, compare)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			unchanged++
+			continue
+		}
+		copied = append(copied, file)
+		if params.Dryrun {
+			continue
+		}
+		if err := syncFile(source, dest); err != nil {
+			return err
+		}
+	}
+	if params.Prune && util.DirExists(params.Todir) {
+		kept := make(map[string]bool)
+		for _, file := range sources {
+			kept[file] = true
+		}
+		dests, err := util.FindFiles(params.Todir, params.Sync, params.Exclude, false)
+		if err != nil {
+			return fmt.Errorf("getting destination files for sync task: %v", err)
+		}
+		for _, file := range dests {
+			if kept[file] {
+				continue
+			}
+			deleted = append(deleted, file)
+			if params.Dryrun {
+				continue
+			}
+			path := filepath.Join(params.Todir, file)
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("deleting file '%s': %v", path, err)
+			}
+		}
+		if !params.Dryrun {
+			if err := removeEmptyDirs(params.Todir, deleted); err != nil {
+				return err
+			}
+		}
+	}
+	if params.Dryrun {
+		for _, file := range copied {
+			context.MessageArgs("Would copy '%s'", file)
+		}
+		for _, file := range deleted {
+			context.MessageArgs("Would delete '%s'", file)
+		}
+		return nil
+	}
+	context.MessageArgs("Synchronized %d file(s) to '%s': %d copied, %d deleted, %d unchanged",
+		len(sources), params.Todir, len(copied), len(deleted), unchanged)
+	return nil
+}
+
+func fileChanged(source, dest, compare string) (bool, error) {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return false, fmt.Errorf("getting info on source file '%s': %v", source, err)
+	}
+	destInfo, err := os.Stat(dest)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("getting info on destination file '%s': %v", dest, err)
+	}
+	if sourceInfo.Size() != destInfo.Size() {
+		return true, nil
+	}
+	if !util.Windows() && sourceInfo.Mode() != destInfo.Mode() {
+		return true, nil
+	}
+	if compare == "time" {
+		// file systems may not store sub-second times
+		return !sourceInfo.ModTime().Truncate(t.Second).Equal(destInfo.ModTime().Truncate(t.Second)), nil
+	}
+	sourceHash, err := fileChecksum(source, sha256.New)
+	if err != nil {
+		return false, err
+	}
+	destHash, err := fileChecksum(dest, sha256.New)
+	if err != nil {
+		return false, err
+	}
+	return sourceHash != destHash, nil
+}
+
+func syncFile(source, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), DirFileMode); err != nil {
+		return fmt.Errorf("creating destination directory: %v", err)
+	}
+	if err := util.CopyFile(source, dest); err != nil {
+		return fmt.Errorf("copying file: %v", err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("getting info on source file '%s': %v", source, err)
+	}
+	if err := os.Chtimes(dest, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("setting modification time of '%s': %v", dest, err)
+	}
+	return nil
+}
+
+// removeEmptyDirs removes directories of deleted files that are empty, from
+// deepest to top, but not root directory
+func removeEmptyDirs(root string, files []string) error {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		for dir := filepath.Dir(file); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	var sorted []string
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	// longer paths first so that children are removed before parents
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for _, dir := range sorted {
+		path := filepath.Join(root, dir)
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("reading directory '%s': %v", path, err)
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("deleting directory '%s': %v", path, err)
+			}
+		}
+	}
+	return nil
+}
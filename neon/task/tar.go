@@ -2,7 +2,6 @@ package task
 
 import (
 	t "archive/tar"
-	"compress/gzip"
 	"fmt"
 	"github.com/c4s4/neon/neon/build"
 	"github.com/c4s4/neon/neon/util"
@@ -11,7 +10,8 @@ import (
 	p "path"
 	"path/filepath"
 	"reflect"
-	"strings"
+	"sort"
+	tm "time"
 )
 
 func init() {
@@ -28,26 +28,43 @@ Arguments:
 - exclude: globs of files to exclude (strings, optional, file, wrap).
 - tofile: name of the tar file to create (string, file).
 - prefix: prefix directory in the archive (optional).
+- format: archive format, one of 'tar', 'tar.gz', 'tar.bz2', 'tar.xz' or
+  'tar.zst', guessed from file name if not set (string, optional).
+- reproducible: create a reproducible archive, with fixed modification times,
+  sorted entries and normalized owner (boolean, optional).
 
 Examples:
 
     # tar files in build directory in file named build.tar.gz
     - tar:    'build/**/*'
       tofile: 'build.tar.gz'
+    # create a reproducible xz compressed archive
+    - tar:          'build/**/*'
+      tofile:       'build.tar.xz'
+      reproducible: true
 
 Notes:
 
-- If archive filename ends with gz (with names such as 'foo.tar.gz' or
-  'foo.tgz') the tar archive is also gzip compressed.`,
+- If format is not set, it is guessed from archive filename extension
+  ('.tar.gz' or '.tgz', '.tar.bz2' or '.tbz2', '.tar.xz' or '.txz',
+  '.tar.zst' or '.tzst'). Other names ending with gz are gzip compressed and
+  all others are not compressed.
+- Formats tar.bz2, tar.xz and tar.zst are compressed with commands bzip2, xz
+  and zstd that must be found in PATH.
+- In reproducible archives, modification time of files is taken from
+  SOURCE_DATE_EPOCH environment variable if set, or January 1, 1980 otherwise.
+  Owner and group are set to root.`,
 	})
 }
 
 type tarArgs struct {
-	Tar     []string `neon:"file,wrap"`
-	Dir     string   `neon:"optional,file"`
-	Exclude []string `neon:"optional,file,wrap"`
-	Tofile  string   `neon:"file"`
-	Prefix  string   `neon:"optional"`
+	Tar          []string `neon:"file,wrap"`
+	Dir          string   `neon:"optional,file"`
+	Exclude      []string `neon:"optional,file,wrap"`
+	Tofile       string   `neon:"file"`
+	Prefix       string   `neon:"optional"`
+	Format       string   `neon:"optional"`
+	Reproducible bool     `neon:"optional"`
 }
 
 func tar(context *build.Context, args interface{}) error {
@@ -58,7 +75,19 @@ func tar(context *build.Context, args interface{}) error {
 	}
 	if len(files) > 0 {
 		context.MessageArgs("Tarring %d file(s) into '%s'", len(files), params.Tofile)
-		err = writeTar(params.Dir, files, params.Prefix, params.Tofile)
+		format, err := tarFormat(params.Tofile, params.Format)
+		if err != nil {
+			return err
+		}
+		var mtime *tm.Time
+		if params.Reproducible {
+			fixed, err := reproducibleTime()
+			if err != nil {
+				return err
+			}
+			mtime = &fixed
+		}
+		err = writeTar(params.Dir, files, params.Prefix, params.Tofile, format, mtime)
 		if err != nil {
 			return fmt.Errorf("tarring files: %v", err)
 		}
@@ -66,7 +95,7 @@ func tar(context *build.Context, args interface{}) error {
 	return nil
 }
 
-func writeTar(dir string, files []string, prefix, to string) error {
+func writeTar(dir string, files []string, prefix, to, format string, mtime *tm.Time) error {
 	stream, err := os.Create(to)
 	if err != nil {
 		return fmt.Errorf("creating tar archive: %v", err)
@@ -74,17 +103,19 @@ func writeTar(dir string, files []string, prefix, to string) error {
 	defer func() {
 		_ = stream.Close()
 	}()
-	var fileWriter io.WriteCloser = stream
-	if strings.HasSuffix(to, "gz") {
-		fileWriter = gzip.NewWriter(stream)
-		defer func() {
-			_ = fileWriter.Close()
-		}()
+	compressor, err := compressWriter(stream, format)
+	if err != nil {
+		return err
+	}
+	writer := t.NewWriter(compressor)
+	if mtime != nil {
+		// entries are sorted by name in archive
+		sorted := append([]string(nil), files...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return SanitizeName(sorted[i]) < SanitizeName(sorted[j])
+		})
+		files = sorted
 	}
-	writer := t.NewWriter(fileWriter)
-	defer func() {
-		_ = writer.Close()
-	}()
 	for _, name := range files {
 		var file string
 		if dir != "" {
@@ -92,15 +123,23 @@ func writeTar(dir string, files []string, prefix, to string) error {
 		} else {
 			file = name
 		}
-		err := writeFileToTar(writer, file, name, prefix)
+		err := writeFileToTar(writer, file, name, prefix, mtime)
 		if err != nil {
+			_ = compressor.Close()
 			return fmt.Errorf("writing stream to tar archive: %v", err)
 		}
 	}
-	return nil
+	if err := writer.Close(); err != nil {
+		_ = compressor.Close()
+		return fmt.Errorf("closing tar archive: %v", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("compressing tar archive: %v", err)
+	}
+	return stream.Close()
 }
 
-func writeFileToTar(writer *t.Writer, file, name, prefix string) error {
+func writeFileToTar(writer *t.Writer, file, name, prefix string, mtime *tm.Time) error {
 	stream, err := os.Open(file)
 	if err != nil {
 		return err
@@ -117,6 +156,15 @@ func writeFileToTar(writer *t.Writer, file, name, prefix string) error {
 		return err
 	}
 	header.Name = p.Join(prefix, SanitizeName(name))
+	if mtime != nil {
+		header.ModTime = *mtime
+		header.AccessTime = tm.Time{}
+		header.ChangeTime = tm.Time{}
+		header.Uid = 0
+		header.Gid = 0
+		header.Uname = "root"
+		header.Gname = "root"
+	}
 	if err = writer.WriteHeader(header); err != nil {
 		return err
 	}
@@ -0,0 +1,150 @@
+package task
+
+import (
+	"fmt"
+	"github.com/c4s4/neon/neon/build"
+	"github.com/c4s4/neon/neon/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "template",
+		Func: templateFunc,
+		Args: reflect.TypeOf(templateArgs{}),
+		Help: `Render template files in a directory.
+
+Arguments:
+
+- template: globs of template files to render (strings, file, wrap).
+- dir: root directory for globs, defaults to '.' (string, optional, file).
+- exclude: globs of files to exclude (strings, optional, file, wrap).
+- todir: directory to write rendered files into (string, file).
+- syntax: template syntax, 'go' for Go text/template or 'neon' for neon
+  expressions in '#{}', defaults to 'go' (string, optional).
+- suffix: suffix to remove from names of rendered files, such as '.tmpl'
+  (string, optional).
+
+Examples:
+
+    # render configuration templates for environment in build directory
+    - template: '*.yml.tmpl'
+      dir:      'config'
+      todir:    'build/config'
+      suffix:   '.tmpl'
+    # render files with neon expressions
+    - template: '**/*.properties'
+      dir:      'config'
+      todir:    'build/config'
+      syntax:   'neon'
+
+Notes:
+
+- With Go syntax, build properties are the data of the template (such as
+  '{{.VERSION}}') and builtins are available as template functions (such as
+  '{{uppercase .NAME}}').
+- With neon syntax, expressions in '#{}' are evaluated in build context, as in
+  strings of the build file (such as '#{uppercase(NAME)}').
+- Rendered files keep the directory structure and the mode of templates.`,
+	})
+}
+
+type templateArgs struct {
+	Template []string `neon:"file,wrap"`
+	Dir      string   `neon:"optional,file"`
+	Exclude  []string `neon:"optional,file,wrap"`
+	Todir    string   `neon:"file"`
+	Syntax   string   `neon:"optional"`
+	Suffix   string   `neon:"optional"`
+}
+
+func templateFunc(context *build.Context, args interface{}) error {
+	params := args.(templateArgs)
+	syntax := params.Syntax
+	if syntax == "" {
+		syntax = "go"
+	}
+	if syntax != "go" && syntax != "neon" {
+		return fmt.Errorf("unknown template syntax '%s' (must be 'go' or 'neon')", syntax)
+	}
+	files, err := util.FindFiles(params.Dir, params.Template, params.Exclude, false)
+	if err != nil {
+		return fmt.Errorf("getting template files: %v", err)
+	}
+	if len(files) < 1 {
+		return nil
+	}
+	var data map[string]interface{}
+	var functions template.FuncMap
+	if syntax == "go" {
+		data, err = context.Properties()
+		if err != nil {
+			return fmt.Errorf("getting properties: %v", err)
+		}
+		functions = templateFunctions(context)
+	}
+	context.MessageArgs("Rendering %d template(s) to '%s'", len(files), params.Todir)
+	for _, file := range files {
+		source := filepath.Join(params.Dir, file)
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return fmt.Errorf("reading template '%s': %v", source, err)
+		}
+		var rendered string
+		if syntax == "go" {
+			rendered, err = renderGoTemplate(file, string(content), data, functions)
+		} else {
+			rendered, err = context.EvaluateString(string(content))
+		}
+		if err != nil {
+			return fmt.Errorf("rendering template '%s': %v", source, err)
+		}
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("getting mode of template '%s': %v", source, err)
+		}
+		dest := filepath.Join(params.Todir, strings.TrimSuffix(file, params.Suffix))
+		if err := os.MkdirAll(filepath.Dir(dest), DirFileMode); err != nil {
+			return fmt.Errorf("creating destination directory: %v", err)
+		}
+		if err := os.WriteFile(dest, []byte(rendered), info.Mode()); err != nil {
+			return fmt.Errorf("writing file '%s': %v", dest, err)
+		}
+	}
+	return nil
+}
+
+// templateFunctions returns builtins as template functions, taken from the VM
+// where they may be disabled in safe mode
+func templateFunctions(context *build.Context) template.FuncMap {
+	functions := make(template.FuncMap)
+	for name := range build.BuiltinMap {
+		function, err := context.GetProperty(name)
+		if err != nil {
+			continue
+		}
+		kind := reflect.TypeOf(function)
+		if kind == nil || kind.Kind() != reflect.Func || kind.NumOut() < 1 || kind.NumOut() > 2 ||
+			(kind.NumOut() == 2 && kind.Out(1) != reflect.TypeOf((*error)(nil)).Elem()) {
+			continue
+		}
+		functions[name] = function
+	}
+	return functions
+}
+
+func renderGoTemplate(name, content string, data map[string]interface{}, functions template.FuncMap) (string, error) {
+	tmpl, err := template.New(name).Funcs(functions).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
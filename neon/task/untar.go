@@ -2,14 +2,12 @@ package task
 
 import (
 	t "archive/tar"
-	"compress/gzip"
 	"fmt"
 	"github.com/c4s4/neon/neon/build"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
-	"strings"
 )
 
 func init() {
@@ -23,36 +21,61 @@ Arguments:
 
 - untar: the tar file to expand (string, file).
 - todir: the destination directory (string, file).
+- format: archive format, one of 'tar', 'tar.gz', 'tar.bz2', 'tar.xz' or
+  'tar.zst', guessed from file name if not set (string, optional).
+- strip: number of leading directories to remove from entry names (integer,
+  optional).
+- include: globs of entry names to expand, all if not set (strings, optional,
+  wrap).
 
 Examples:
 
     # untar foo.tar to build directory
     - untar: 'foo.tar'
       todir: 'build'
+    # expand text files of foo.tar.xz without root directory of the archive
+    - untar:   'foo.tar.xz'
+      todir:   'build'
+      strip:   1
+      include: '**/*.txt'
 
 Notes:
 
-- If archive filename ends with .gz (with a name such as foo.tar.gz or foo.tgz)
-  the tar archive is uncompressed with gzip.`,
+- If format is not set, it is guessed from archive filename extension
+  ('.tar.gz' or '.tgz', '.tar.bz2' or '.tbz2', '.tar.xz' or '.txz',
+  '.tar.zst' or '.tzst'). Other names ending with gz are gzip compressed and
+  all others are not compressed.
+- Formats tar.xz and tar.zst are uncompressed with commands xz and zstd that
+  must be found in PATH.
+- Include globs are matched against entry names in the archive, before
+  leading directories are stripped. Entries with less directories than strip
+  are skipped.`,
 	})
 }
 
 type untarArgs struct {
-	Untar string `neon:"file"`
-	Todir string `neon:"file"`
+	Untar   string   `neon:"file"`
+	Todir   string   `neon:"file"`
+	Format  string   `neon:"optional"`
+	Strip   int      `neon:"optional"`
+	Include []string `neon:"optional,wrap"`
 }
 
 func untar(context *build.Context, args interface{}) error {
 	params := args.(untarArgs)
 	context.MessageArgs("Untarring archive '%s' to directory '%s'...", params.Untar, params.Todir)
-	err := untarFile(params.Untar, params.Todir)
+	format, err := tarFormat(params.Untar, params.Format)
+	if err != nil {
+		return err
+	}
+	err = untarFile(params.Untar, params.Todir, format, params.Strip, params.Include)
 	if err != nil {
 		return fmt.Errorf("expanding archive: %v", err)
 	}
 	return nil
 }
 
-func untarFile(file, dir string) error {
+func untarFile(file, dir, format string, strip int, includes []string) error {
 	reader, err := os.Open(file)
 	if err != nil {
 		return fmt.Errorf("opening source tar file %s: %v", file, err)
@@ -60,38 +83,41 @@ func untarFile(file, dir string) error {
 	defer func() {
 		_ = reader.Close()
 	}()
-	var tarReader *t.Reader
-	if strings.HasSuffix(file, ".gz") || strings.HasSuffix(file, ".tgz") {
-		gzipReader, err := gzip.NewReader(reader)
-		if err != nil {
-			return fmt.Errorf("unzipping tar file: %v", err)
-		}
-		defer func() {
-			_ = gzipReader.Close()
-		}()
-		tarReader = t.NewReader(gzipReader)
-	} else {
-		tarReader = t.NewReader(reader)
+	decompressor, err := decompressReader(reader, format)
+	if err != nil {
+		return fmt.Errorf("uncompressing tar file: %v", err)
 	}
+	closed := false
+	defer func() {
+		if !closed {
+			_ = decompressor.Close()
+		}
+	}()
+	tarReader := t.NewReader(decompressor)
 	for {
 		header, err := tarReader.Next()
 		switch {
 		case err == io.EOF:
-			return nil
+			// external decompressors report corrupted streams on close
+			closed = true
+			return decompressor.Close()
 		case err != nil:
 			return err
 		case header == nil:
 			continue
 		}
-		target := filepath.Join(dir, header.Name)
-		if header.Typeflag == t.TypeReg {
+		target, err := entryPath(header.Name, dir, strip, includes)
+		if err != nil {
+			return err
+		}
+		if target != "" && header.Typeflag == t.TypeReg {
 			destination := filepath.Dir(target)
 			if _, err := os.Stat(destination); err != nil {
 				if err := os.MkdirAll(destination, 0755); err != nil {
 					return fmt.Errorf("creating destination director %s: %v", target, err)
 				}
 			}
-			dest, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
+			dest, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(header.Mode))
 			if err != nil {
 				return fmt.Errorf("creating destination file %s: %v", target, err)
 			}
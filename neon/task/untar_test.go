@@ -0,0 +1,37 @@
+package task
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestUntarCorruptedStream(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz is not in PATH")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("test"), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "archive.tar.xz")
+	if err := writeTar(dir, []string{"file.txt"}, "", archive, "tar.xz", nil); err != nil {
+		t.Fatalf("writing archive: %v", err)
+	}
+	if err := untarFile(archive, filepath.Join(dir, "good"), "tar.xz", 0, nil); err != nil {
+		t.Fatalf("extracting archive: %v", err)
+	}
+	// garbage after a complete tar stream is only detected by xz
+	file, err := os.OpenFile(archive, os.O_APPEND|os.O_WRONLY, FileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	if err := untarFile(archive, filepath.Join(dir, "bad"), "tar.xz", 0, nil); err == nil {
+		t.Errorf("extracting corrupted archive should fail")
+	}
+}
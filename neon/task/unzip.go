@@ -21,31 +21,51 @@ Arguments:
 
 - unzip: the zip file to expand (string, file).
 - todir: the destination directory (string, file).
+- strip: number of leading directories to remove from entry names (integer,
+  optional).
+- include: globs of entry names to expand, all if not set (strings, optional,
+  wrap).
 
 Examples:
 
     # unzip foo.zip to build directory
     - unzip: 'foo.zip'
-      todir: 'build'`,
+      todir: 'build'
+    # expand text files of foo.zip without root directory of the archive
+    - unzip:   'foo.zip'
+      todir:   'build'
+      strip:   1
+      include: '**/*.txt'
+
+Notes:
+
+- File modes stored in the archive are restored.
+- Symbolic links stored in the archive are restored as links. Links with an
+  absolute target or pointing outside of destination directory are an error.
+- Include globs are matched against entry names in the archive, before
+  leading directories are stripped. Entries with less directories than strip
+  are skipped.`,
 	})
 }
 
 type unzipArgs struct {
-	Unzip string `neon:"file"`
-	Todir string `neon:"file"`
+	Unzip   string   `neon:"file"`
+	Todir   string   `neon:"file"`
+	Strip   int      `neon:"optional"`
+	Include []string `neon:"optional,wrap"`
 }
 
 func unzip(context *build.Context, args interface{}) error {
 	params := args.(unzipArgs)
 	context.MessageArgs("Unzipping archive '%s' to directory '%s'...", params.Unzip, params.Todir)
-	err := unzipFile(params.Unzip, params.Todir)
+	err := unzipFile(params.Unzip, params.Todir, params.Strip, params.Include)
 	if err != nil {
 		return fmt.Errorf("expanding archive: %v", err)
 	}
 	return nil
 }
 
-func unzipFile(file, dir string) error {
+func unzipFile(file, dir string, strip int, includes []string) error {
 	reader, err := z.OpenReader(file)
 	if err != nil {
 		return fmt.Errorf("opening source zip file %s: %v", file, err)
@@ -54,35 +74,72 @@ func unzipFile(file, dir string) error {
 		_ = reader.Close()
 	}()
 	for _, file := range reader.File {
-		readCloser, err := file.Open()
+		target, err := entryPath(file.Name, dir, strip, includes)
 		if err != nil {
-			return fmt.Errorf("opening zip file %s: %v", file.Name, err)
+			return err
 		}
-		defer func() {
-			_ = readCloser.Close()
-		}()
-		target := filepath.Join(dir, file.Name)
-		if file.FileInfo().IsDir() {
+		if target == "" {
 			continue
-		} else {
-			destination := filepath.Dir(target)
-			if _, err := os.Stat(destination); err != nil {
-				if err := os.MkdirAll(destination, 0755); err != nil {
-					return fmt.Errorf("creating destination directory '%s': %v", target, err)
-				}
-			}
-			dest, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-			if err != nil {
-				return fmt.Errorf("creating destination file '%s': %v", target, err)
-			}
-			defer func() {
-				_ = dest.Close()
-			}()
-			_, err = io.Copy(dest, readCloser)
-			if err != nil {
-				return fmt.Errorf("copying to destination file '%s': %v", target, err)
-			}
 		}
+		if err := unzipEntry(file, target, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipEntry(file *z.File, target, dir string) error {
+	mode := file.Mode()
+	if mode.IsDir() {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("creating destination directory '%s': %v", target, err)
+		}
+		return nil
+	}
+	destination := filepath.Dir(target)
+	if _, err := os.Stat(destination); err != nil {
+		if err := os.MkdirAll(destination, 0755); err != nil {
+			return fmt.Errorf("creating destination directory '%s': %v", target, err)
+		}
+	}
+	readCloser, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("opening zip file %s: %v", file.Name, err)
+	}
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	if mode&os.ModeSymlink != 0 {
+		link, err := io.ReadAll(readCloser)
+		if err != nil {
+			return fmt.Errorf("reading link '%s': %v", file.Name, err)
+		}
+		linkTarget := filepath.FromSlash(string(link))
+		if err := checkLink(target, linkTarget, dir); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("removing destination file '%s': %v", target, err)
+		}
+		if err := os.Symlink(linkTarget, target); err != nil {
+			return fmt.Errorf("creating link '%s': %v", target, err)
+		}
+		return nil
+	}
+	dest, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return fmt.Errorf("creating destination file '%s': %v", target, err)
+	}
+	defer func() {
+		_ = dest.Close()
+	}()
+	_, err = io.Copy(dest, readCloser)
+	if err != nil {
+		return fmt.Errorf("copying to destination file '%s': %v", target, err)
+	}
+	// file mode is set regardless of umask
+	if err := dest.Chmod(mode.Perm()); err != nil {
+		return fmt.Errorf("setting mode of destination file '%s': %v", target, err)
 	}
 	return nil
 }
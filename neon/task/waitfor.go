@@ -0,0 +1,115 @@
+package task
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/c4s4/neon/neon/build"
+)
+
+func init() {
+	build.AddTask(build.TaskDesc{
+		Name: "waitfor",
+		Func: waitfor,
+		Args: reflect.TypeOf(waitforArgs{}),
+		Help: `Wait until a TCP port accepts connections, a URL responds, a file exists or
+a command succeeds.
+
+Arguments:
+
+- waitfor: name of what we are waiting for, printed on console (string).
+- port: wait until this TCP port accepts connections (int, optional).
+- host: host of the port, defaults to 'localhost' (string, optional).
+- url: wait until a request to this URL returns expected status (string,
+  optional).
+- method: request method, defaults to 'GET' (string, optional).
+- headers: request headers (map with string keys and values, optional).
+- status: expected status of the response, defaults to 200 (int, optional).
+- file: wait until this file or directory exists (string, optional, file).
+- command: wait until this command succeeds (strings, optional).
+- timeout: time to wait, in seconds, defaults to 30 (float, optional).
+- interval: delay between two attempts, in seconds, defaults to 0.2 (float,
+  optional).
+
+Examples:
+
+    # wait for database port
+    - waitfor: 'database'
+      port:    5432
+    # wait for health check of a server
+    - waitfor: 'server'
+      url:     'http://localhost:8080/health'
+      timeout: 60.0
+    # wait until a file is generated
+    - waitfor: 'report'
+      file:    'build/report.html'
+    # wait until a command succeeds
+    - waitfor:  'docker'
+      command:  ['docker', 'info']
+      interval: 1.0
+
+Notes:
+
+- When many conditions are set, task waits until all of them hold.
+- As with $ task, a command defined as a string runs in the shell defined by
+  shell field at the root of the build file, and a command defined as a list
+  runs without shell. Its output is discarded.
+- This task was not named wait as it would clash with wait argument of
+  singleton task.`,
+	})
+}
+
+type waitforArgs struct {
+	Waitfor  string
+	Port     int               `neon:"optional"`
+	Host     string            `neon:"optional"`
+	Url      string            `neon:"optional"`
+	Method   string            `neon:"optional"`
+	Headers  map[string]string `neon:"optional"`
+	Status   int               `neon:"optional"`
+	File     string            `neon:"optional,file"`
+	Command  []string          `neon:"optional,wrap"`
+	Timeout  float64           `neon:"optional"`
+	Interval float64           `neon:"optional"`
+}
+
+func waitfor(context *build.Context, args interface{}) error {
+	params := args.(waitforArgs)
+	var probes []probe
+	if params.Port != 0 {
+		host := params.Host
+		if host == "" {
+			host = "localhost"
+		}
+		probes = append(probes, portProbe(host, params.Port))
+	}
+	if params.Url != "" {
+		status := params.Status
+		if status == 0 {
+			status = DefaultStatus
+		}
+		probes = append(probes, urlProbe(params.Url, params.Method, params.Headers, status))
+	}
+	if params.File != "" {
+		probes = append(probes, fileProbe(params.File))
+	}
+	if len(params.Command) > 0 {
+		probes = append(probes, commandProbe(context, params.Command))
+	}
+	if len(probes) == 0 {
+		return fmt.Errorf("one of port, url, file or command must be set")
+	}
+	timeout := params.Timeout
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	interval := params.Interval
+	if interval == 0 {
+		interval = DefaultProbeInterval
+	}
+	context.MessageArgs("Waiting for %s...", params.Waitfor)
+	if err := waitProbes(probes, timeout, interval, nil); err != nil {
+		return fmt.Errorf("waiting for %s: %v", params.Waitfor, err)
+	}
+	return nil
+}
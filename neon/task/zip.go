@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	t "time"
 )
 
 func init() {
@@ -26,21 +28,38 @@ Arguments:
 - exclude: globs of files to exclude (strings, optional, file, wrap).
 - tofile: name of the Zip file to create (string, file).
 - prefix: prefix directory in the archive (string, optional).
+- symlinks: store symbolic links as links instead of the files they point to
+  (boolean, optional).
+- reproducible: create a reproducible archive, with fixed modification times
+  and sorted entries (boolean, optional).
 
 Examples:
 
     # zip files of build directory in file named build.zip
     - zip:    'build/**/*'
-      tofile: 'build.zip'`,
+      tofile: 'build.zip'
+    # create a reproducible archive keeping symbolic links
+    - zip:          'build/**/*'
+      tofile:       'build.zip'
+      symlinks:     true
+      reproducible: true
+
+Notes:
+
+- File modes are stored in the archive and restored by unzip task.
+- In reproducible archives, modification time of files is taken from
+  SOURCE_DATE_EPOCH environment variable if set, or January 1, 1980 otherwise.`,
 	})
 }
 
 type zipArgs struct {
-	Zip     []string `neon:"file,wrap"`
-	Dir     string   `neon:"optional,file"`
-	Exclude []string `neon:"optional,file,wrap"`
-	Tofile  string   `neon:"file"`
-	Prefix  string   `neon:"optional"`
+	Zip          []string `neon:"file,wrap"`
+	Dir          string   `neon:"optional,file"`
+	Exclude      []string `neon:"optional,file,wrap"`
+	Tofile       string   `neon:"file"`
+	Prefix       string   `neon:"optional"`
+	Symlinks     bool     `neon:"optional"`
+	Reproducible bool     `neon:"optional"`
 }
 
 func zip(context *build.Context, args interface{}) error {
@@ -51,7 +70,15 @@ func zip(context *build.Context, args interface{}) error {
 	}
 	if len(files) > 0 {
 		context.MessageArgs("Zipping %d file(s) in '%s'", len(files), params.Tofile)
-		err = writeZip(params.Dir, files, params.Prefix, params.Tofile)
+		var mtime *t.Time
+		if params.Reproducible {
+			fixed, err := reproducibleTime()
+			if err != nil {
+				return err
+			}
+			mtime = &fixed
+		}
+		err = writeZip(params.Dir, files, params.Prefix, params.Tofile, params.Symlinks, mtime)
 		if err != nil {
 			return fmt.Errorf("zipping files: %v", err)
 		}
@@ -59,7 +86,7 @@ func zip(context *build.Context, args interface{}) error {
 	return nil
 }
 
-func writeZip(dir string, files []string, prefix, to string) error {
+func writeZip(dir string, files []string, prefix, to string, symlinks bool, mtime *t.Time) error {
 	archive, err := os.Create(to)
 	if err != nil {
 		return fmt.Errorf("creating zip archive: %v", err)
@@ -74,6 +101,14 @@ func writeZip(dir string, files []string, prefix, to string) error {
 	defer func() {
 		_ = zipper.Close()
 	}()
+	if mtime != nil {
+		// entries are sorted by name in archive
+		sorted := append([]string(nil), files...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return SanitizeName(sorted[i]) < SanitizeName(sorted[j])
+		})
+		files = sorted
+	}
 	for _, file := range files {
 		var path string
 		if dir != "" {
@@ -81,7 +116,7 @@ func writeZip(dir string, files []string, prefix, to string) error {
 		} else {
 			path = file
 		}
-		err := writeFileToZip(zipper, path, file, prefix)
+		err := writeFileToZip(zipper, path, file, prefix, symlinks, mtime, z.Store)
 		if err != nil {
 			return fmt.Errorf("writing file to zip archive: %v", err)
 		}
@@ -89,15 +124,14 @@ func writeZip(dir string, files []string, prefix, to string) error {
 	return nil
 }
 
-func writeFileToZip(zipper *z.Writer, path, name, prefix string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+func writeFileToZip(zipper *z.Writer, path, name, prefix string, symlinks bool, mtime *t.Time, method uint16) error {
+	var info os.FileInfo
+	var err error
+	if symlinks {
+		info, err = os.Lstat(path)
+	} else {
+		info, err = os.Stat(path)
 	}
-	defer func() {
-		_ = file.Close()
-	}()
-	info, err := file.Stat()
 	if err != nil {
 		return err
 	}
@@ -110,6 +144,31 @@ func writeFileToZip(zipper *z.Writer, path, name, prefix string) error {
 		name = prefix + "/" + name
 	}
 	header.Name = name
+	if mtime != nil {
+		header.Modified = *mtime
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		// link target is stored as content of the entry
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		header.Method = z.Store
+		writer, err := zipper.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write([]byte(filepath.ToSlash(target)))
+		return err
+	}
+	header.Method = method
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
 	writer, err := zipper.CreateHeader(header)
 	if err != nil {
 		return err
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EncryptedHeader starts the first line of encrypted files, followed with
+// the number of iterations of key derivation
+const EncryptedHeader = "$NEON;AES256-GCM;PBKDF2-SHA256"
+
+// KeyIterations is the number of iterations to derive key from passphrase
+const KeyIterations = 600000
+
+const (
+	// length of the random salt for key derivation
+	saltLength = 16
+	// maximum number of iterations accepted when decrypting
+	maxKeyIterations = 100000000
+)
+
+// encryptedWidth is the width of lines of base64 encoded encrypted data
+const encryptedWidth = 64
+
+// IsEncrypted tells if given data was encrypted with Encrypt
+// - data: the data to test
+// Return: a boolean that tells if data starts with encrypted header
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EncryptedHeader+";"))
+}
+
+// Encrypt encrypts data with AES-256 in GCM mode. The encryption key is
+// derived from the passphrase with PBKDF2-SHA256 and a random salt. Result is
+// a text with a header and base64 encoded salt, nonce and encrypted data.
+// - data: the data to encrypt
+// - passphrase: the passphrase to derive key from
+// Return:
+// - encrypted data
+// - an error if something went wrong
+func Encrypt(data, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %v", err)
+	}
+	gcm, err := newGCM(passphrase, salt, KeyIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %v", err)
+	}
+	sealed := gcm.Seal(append(salt, nonce...), nonce, data, nil)
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+	var builder strings.Builder
+	builder.WriteString(EncryptedHeader + ";" + strconv.Itoa(KeyIterations) + "\n")
+	for len(encoded) > encryptedWidth {
+		builder.WriteString(encoded[:encryptedWidth] + "\n")
+		encoded = encoded[encryptedWidth:]
+	}
+	builder.WriteString(encoded + "\n")
+	return []byte(builder.String()), nil
+}
+
+// Decrypt decrypts data encrypted with Encrypt:
+// - data: the encrypted data
+// - passphrase: the passphrase to derive key from
+// Return:
+// - decrypted data
+// - an error if something went wrong
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	header, body, _ := strings.Cut(string(data), "\n")
+	iterations, err := strconv.Atoi(strings.TrimSpace(header[len(EncryptedHeader)+1:]))
+	if err != nil || iterations < 1 || iterations > maxKeyIterations {
+		return nil, fmt.Errorf("bad number of key iterations in header")
+	}
+	encoded := strings.Join(strings.Fields(body), "")
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding encrypted data: %v", err)
+	}
+	if len(sealed) < saltLength {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	salt, sealed := sealed[:saltLength], sealed[saltLength:]
+	gcm, err := newGCM(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting data: bad key or corrupted data")
+	}
+	return plain, nil
+}
+
+// newGCM derives the key from passphrase and builds the cipher
+func newGCM(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty encryption key")
+	}
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %v", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	data := []byte("PASSWORD: 'secret'\n")
+	encrypted, err := Encrypt(data, []byte("key"))
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Errorf("encrypted data should start with header")
+	}
+	if IsEncrypted(data) {
+		t.Errorf("plain data should not be detected as encrypted")
+	}
+	decrypted, err := Decrypt(encrypted, []byte("key"))
+	if err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+	if string(decrypted) != string(data) {
+		t.Errorf("bad decrypted data: %s", decrypted)
+	}
+	if _, err := Decrypt(encrypted, []byte("bad")); err == nil {
+		t.Errorf("decrypting with bad key should fail")
+	}
+}
+
+func TestEncryptSalt(t *testing.T) {
+	data := []byte("PASSWORD: 'secret'\n")
+	first, err := Encrypt(data, []byte("key"))
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	second, err := Encrypt(data, []byte("key"))
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Errorf("encrypting twice should give different results")
+	}
+	header := EncryptedHeader + ";600000\n"
+	if !strings.HasPrefix(string(first), header) {
+		t.Errorf("bad header: %s", first)
+	}
+	bad := strings.Replace(string(first), header, EncryptedHeader+";foo\n", 1)
+	if _, err := Decrypt([]byte(bad), []byte("key")); err == nil {
+		t.Errorf("bad number of iterations should fail")
+	}
+}
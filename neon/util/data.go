@@ -0,0 +1,484 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DataFormats maps file extensions to structured data formats
+var DataFormats = map[string]string{
+	".yml":  "yaml",
+	".yaml": "yaml",
+	".json": "json",
+	".toml": "toml",
+}
+
+// regexp to guess indentation of JSON files
+var jsonIndent = regexp.MustCompile(`\n([ \t]+)\S`)
+
+// DataEdit is a modification of structured data at a path
+type DataEdit struct {
+	Path  string
+	Value interface{}
+	Unset bool
+}
+
+// pathElement is an element of a path in data, a key or an index
+type pathElement struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (element pathElement) String() string {
+	if element.isIndex {
+		return fmt.Sprintf("[%d]", element.index)
+	}
+	return element.key
+}
+
+// DataFormat returns the format of a structured data file:
+// - file: the file name
+// - format: the format set by user, guessed from extension if empty
+// Return: the format ('yaml', 'json' or 'toml') and an error if unknown
+func DataFormat(file, format string) (string, error) {
+	if format == "" {
+		format = DataFormats[strings.ToLower(filepath.Ext(file))]
+		if format == "" {
+			return "", fmt.Errorf("can't guess data format of file '%s'", file)
+		}
+	}
+	if format != "yaml" && format != "json" && format != "toml" {
+		return "", fmt.Errorf("unknown data format '%s' (must be 'yaml', 'json' or 'toml')", format)
+	}
+	return format, nil
+}
+
+// parseDataPath parses a path such as 'a.b[2].c' or 'a["b.c"]'
+func parseDataPath(path string) ([]pathElement, error) {
+	var elements []pathElement
+	key := ""
+	inKey := false
+	for i := 0; i < len(path); i++ {
+		char := path[i]
+		switch char {
+		case '.':
+			if inKey {
+				elements = append(elements, pathElement{key: key})
+				key, inKey = "", false
+			} else if i == 0 || path[i-1] != ']' {
+				return nil, fmt.Errorf("empty key in path '%s'", path)
+			}
+		case '[':
+			if inKey {
+				elements = append(elements, pathElement{key: key})
+				key, inKey = "", false
+			}
+			end := strings.Index(path[i:], "]")
+			if end < 0 {
+				return nil, fmt.Errorf("missing ']' in path '%s'", path)
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				unquoted, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("bad quoted key in path '%s'", path)
+				}
+				elements = append(elements, pathElement{key: unquoted})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("bad index '%s' in path '%s'", inner, path)
+				}
+				elements = append(elements, pathElement{index: index, isIndex: true})
+			}
+			i += end
+		default:
+			key += string(char)
+			inKey = true
+		}
+	}
+	if inKey {
+		elements = append(elements, pathElement{key: key})
+	} else if len(path) == 0 || path[len(path)-1] == '.' {
+		return nil, fmt.Errorf("empty key in path '%s'", path)
+	}
+	return elements, nil
+}
+
+// GetDataPath returns the value at given path in structured data:
+// - content: the content of the data file
+// - format: the format of data ('yaml', 'json' or 'toml')
+// - path: the path of the value, such as 'a.b[2].c'
+// Return: the value and an error if path was not found
+func GetDataPath(content []byte, format, path string) (interface{}, error) {
+	elements, err := parseDataPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "toml" {
+		return tomlGet(content, elements)
+	}
+	data, err := decodeData(content, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range elements {
+		data, err = getChild(data, element)
+		if err != nil {
+			return nil, fmt.Errorf("getting path '%s': %v", path, err)
+		}
+	}
+	return plainData(data), nil
+}
+
+// EditData sets and unsets values in structured data, keeping order of keys
+// (and comments for YAML, formatting for TOML):
+// - content: the content of the data file
+// - format: the format of data ('yaml', 'json' or 'toml')
+// - edits: the modifications to apply
+// Return: modified content and an error if something went wrong
+func EditData(content []byte, format string, edits []DataEdit) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return yamlEdit(content, edits)
+	case "toml":
+		return tomlEdit(content, edits)
+	}
+	data, err := decodeData(content, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, edit := range edits {
+		elements, err := parseDataPath(edit.Path)
+		if err != nil {
+			return nil, err
+		}
+		if edit.Unset {
+			data, err = unsetPath(data, elements)
+		} else {
+			data, err = setPath(data, elements, orderedData(edit.Value))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("editing path '%s': %v", edit.Path, err)
+		}
+	}
+	return encodeData(data, format, content)
+}
+
+func decodeData(content []byte, format string) (interface{}, error) {
+	if format == "json" {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		data, err := decodeJSON(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("decoding JSON: %v", err)
+		}
+		return data, nil
+	}
+	var mapping yaml.MapSlice
+	if err := yaml.Unmarshal(content, &mapping); err == nil {
+		return mapping, nil
+	}
+	// root is not a mapping
+	var data interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("decoding YAML: %v", err)
+	}
+	return orderedData(data), nil
+}
+
+// decodeJSON decodes JSON keeping order of keys in MapSlices
+func decodeJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		mapping := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			mapping = append(mapping, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err = decoder.Token()
+		return mapping, err
+	case '[':
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return nil, fmt.Errorf("unexpected delimiter '%v'", delim)
+}
+
+func encodeData(data interface{}, format string, original []byte) ([]byte, error) {
+	if format == "yaml" {
+		return yaml.Marshal(data)
+	}
+	indent := "  "
+	if match := jsonIndent.FindSubmatch(original); match != nil {
+		indent = string(match[1])
+	}
+	var builder strings.Builder
+	if err := encodeJSON(&builder, data, indent, ""); err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(original, []byte("\n")) {
+		builder.WriteString("\n")
+	}
+	return []byte(builder.String()), nil
+}
+
+// encodeJSON encodes data in JSON with indentation, keeping order of keys
+func encodeJSON(builder *strings.Builder, data interface{}, indent, prefix string) error {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		if len(value) == 0 {
+			builder.WriteString("{}")
+			return nil
+		}
+		builder.WriteString("{\n")
+		for i, item := range value {
+			builder.WriteString(prefix + indent)
+			if err := encodeJSON(builder, fmt.Sprint(item.Key), indent, prefix+indent); err != nil {
+				return err
+			}
+			builder.WriteString(": ")
+			if err := encodeJSON(builder, item.Value, indent, prefix+indent); err != nil {
+				return err
+			}
+			if i < len(value)-1 {
+				builder.WriteString(",")
+			}
+			builder.WriteString("\n")
+		}
+		builder.WriteString(prefix + "}")
+	case []interface{}:
+		if len(value) == 0 {
+			builder.WriteString("[]")
+			return nil
+		}
+		builder.WriteString("[\n")
+		for i, item := range value {
+			builder.WriteString(prefix + indent)
+			if err := encodeJSON(builder, item, indent, prefix+indent); err != nil {
+				return err
+			}
+			if i < len(value)-1 {
+				builder.WriteString(",")
+			}
+			builder.WriteString("\n")
+		}
+		builder.WriteString(prefix + "]")
+	default:
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("encoding JSON: %v", err)
+		}
+		builder.WriteString(strings.TrimSuffix(buffer.String(), "\n"))
+	}
+	return nil
+}
+
+// orderedData converts maps to MapSlices, with sorted keys
+func orderedData(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[interface{}]interface{}:
+		mapping := yaml.MapSlice{}
+		for key, item := range value {
+			mapping = append(mapping, yaml.MapItem{Key: key, Value: orderedData(item)})
+		}
+		sort.Slice(mapping, func(i, j int) bool {
+			return fmt.Sprint(mapping[i].Key) < fmt.Sprint(mapping[j].Key)
+		})
+		return mapping
+	case map[string]interface{}:
+		generic := make(map[interface{}]interface{})
+		for key, item := range value {
+			generic[key] = item
+		}
+		return orderedData(generic)
+	case yaml.MapSlice:
+		mapping := yaml.MapSlice{}
+		for _, item := range value {
+			mapping = append(mapping, yaml.MapItem{Key: item.Key, Value: orderedData(item.Value)})
+		}
+		return mapping
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = orderedData(item)
+		}
+		return list
+	case []string:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = item
+		}
+		return list
+	}
+	return data
+}
+
+// plainData converts MapSlices to maps and JSON numbers to integers or floats
+func plainData(data interface{}) interface{} {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		mapping := make(map[interface{}]interface{})
+		for _, item := range value {
+			mapping[item.Key] = plainData(item.Value)
+		}
+		return mapping
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = plainData(item)
+		}
+		return list
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	}
+	return data
+}
+
+func getChild(data interface{}, element pathElement) (interface{}, error) {
+	if element.isIndex {
+		list, ok := data.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("value at %s is not a list", element)
+		}
+		if element.index >= len(list) {
+			return nil, fmt.Errorf("index %s out of range", element)
+		}
+		return list[element.index], nil
+	}
+	mapping, ok := data.(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("value at %s is not a map", element)
+	}
+	for _, item := range mapping {
+		if fmt.Sprint(item.Key) == element.key {
+			return item.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("key '%s' not found", element.key)
+}
+
+func setPath(data interface{}, elements []pathElement, value interface{}) (interface{}, error) {
+	if len(elements) == 0 {
+		return value, nil
+	}
+	element := elements[0]
+	if element.isIndex {
+		list, ok := data.([]interface{})
+		if !ok && data != nil {
+			return nil, fmt.Errorf("value at %s is not a list", element)
+		}
+		if element.index < len(list) {
+			child, err := setPath(list[element.index], elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			list[element.index] = child
+		} else if element.index == len(list) {
+			child, err := setPath(nil, elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, child)
+		} else {
+			return nil, fmt.Errorf("index %s out of range", element)
+		}
+		return list, nil
+	}
+	mapping, ok := data.(yaml.MapSlice)
+	if !ok && data != nil {
+		return nil, fmt.Errorf("value at %s is not a map", element)
+	}
+	for i, item := range mapping {
+		if fmt.Sprint(item.Key) == element.key {
+			child, err := setPath(item.Value, elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			mapping[i].Value = child
+			return mapping, nil
+		}
+	}
+	child, err := setPath(nil, elements[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	return append(mapping, yaml.MapItem{Key: element.key, Value: child}), nil
+}
+
+// unsetPath removes value at path, doing nothing if path is not found
+func unsetPath(data interface{}, elements []pathElement) (interface{}, error) {
+	element := elements[0]
+	last := len(elements) == 1
+	if element.isIndex {
+		list, ok := data.([]interface{})
+		if !ok || element.index >= len(list) {
+			return data, nil
+		}
+		if last {
+			return append(list[:element.index], list[element.index+1:]...), nil
+		}
+		child, err := unsetPath(list[element.index], elements[1:])
+		if err != nil {
+			return nil, err
+		}
+		list[element.index] = child
+		return list, nil
+	}
+	mapping, ok := data.(yaml.MapSlice)
+	if !ok {
+		return data, nil
+	}
+	for i, item := range mapping {
+		if fmt.Sprint(item.Key) == element.key {
+			if last {
+				return append(mapping[:i], mapping[i+1:]...), nil
+			}
+			child, err := unsetPath(item.Value, elements[1:])
+			if err != nil {
+				return nil, err
+			}
+			mapping[i].Value = child
+			return mapping, nil
+		}
+	}
+	return mapping, nil
+}
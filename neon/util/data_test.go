@@ -0,0 +1,149 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestParseDataPath(t *testing.T) {
+	elements, err := parseDataPath(`a.b[2].c["d.e"]`)
+	if err != nil {
+		t.Fatalf("parsing path: %v", err)
+	}
+	Assert(len(elements), 5, t)
+	Assert(elements[0].key, "a", t)
+	Assert(elements[2].isIndex, true, t)
+	Assert(elements[2].index, 2, t)
+	Assert(elements[4].key, "d.e", t)
+	for _, path := range []string{"", "a..b", ".a", "a.", "a[x]", "a[1"} {
+		if _, err := parseDataPath(path); err == nil {
+			t.Errorf("path '%s' should be invalid", path)
+		}
+	}
+}
+
+func TestEditDataYAML(t *testing.T) {
+	content := []byte("name: chart\nversion: 1.0.0\ndependencies:\n- name: foo\n  version: 1.2.3\n")
+	edited, err := EditData(content, "yaml", []DataEdit{
+		{Path: "version", Value: "1.1.0"},
+		{Path: "dependencies[0].version", Value: "2.0.0"},
+		{Path: "maintainers[0].name", Value: "me"},
+		{Path: "name", Unset: true},
+	})
+	if err != nil {
+		t.Fatalf("editing YAML: %v", err)
+	}
+	Assert(string(edited), "version: 1.1.0\ndependencies:\n  - name: foo\n    version: 2.0.0\nmaintainers:\n  - name: me\n", t)
+	value, err := GetDataPath(edited, "yaml", "dependencies[0].version")
+	if err != nil {
+		t.Fatalf("getting path: %v", err)
+	}
+	Assert(value, "2.0.0", t)
+	if _, err := GetDataPath(edited, "yaml", "dependencies[1]"); err == nil {
+		t.Errorf("getting missing path should fail")
+	}
+}
+
+func TestEditDataYAMLComments(t *testing.T) {
+	content := []byte("# chart\nversion: \"1.0.0\" # bump\ntags: [a, b]\nempty:\ndescription: |\n    multi\n    line\n")
+	edited, err := EditData(content, "yaml", []DataEdit{
+		{Path: "version", Value: "1.0.1"},
+		{Path: "tags", Value: []interface{}{"c"}},
+		{Path: "empty.key", Value: 1},
+	})
+	if err != nil {
+		t.Fatalf("editing YAML: %v", err)
+	}
+	Assert(string(edited), "# chart\nversion: \"1.0.1\" # bump\ntags: [c]\nempty:\n    key: 1\ndescription: |\n    multi\n    line\n", t)
+}
+
+func TestEditDataJSON(t *testing.T) {
+	content := []byte("{\n    \"name\": \"app\",\n    \"version\": \"1.0.0\",\n    \"scripts\": {\n        \"test\": \"jest\"\n    },\n    \"count\": 3\n}\n")
+	edited, err := EditData(content, "json", []DataEdit{
+		{Path: "version", Value: "1.1.0"},
+		{Path: "scripts.build", Value: "tsc && <done>"},
+	})
+	if err != nil {
+		t.Fatalf("editing JSON: %v", err)
+	}
+	Assert(string(edited), "{\n    \"name\": \"app\",\n    \"version\": \"1.1.0\",\n    \"scripts\": {\n        \"test\": \"jest\",\n        \"build\": \"tsc && <done>\"\n    },\n    \"count\": 3\n}\n", t)
+	value, err := GetDataPath(edited, "json", "count")
+	if err != nil {
+		t.Fatalf("getting path: %v", err)
+	}
+	Assert(value, int64(3), t)
+}
+
+func TestEditDataTOML(t *testing.T) {
+	content := []byte("# project\nname = \"app\"\n\n[package]\nversion = \"1.0.0\" # bumped\nauthors = ['me', \"you\"]\n\n[deps]\nfoo = 1\n")
+	edited, err := EditData(content, "toml", []DataEdit{
+		{Path: "package.version", Value: "1.1.0"},
+		{Path: "package.edition", Value: 2021},
+		{Path: "deps.foo", Unset: true},
+		{Path: "build.release", Value: true},
+		{Path: "build.command", Value: "make && <done>"},
+	})
+	if err != nil {
+		t.Fatalf("editing TOML: %v", err)
+	}
+	Assert(string(edited), "# project\nname = \"app\"\n\n[package]\nversion = \"1.1.0\" # bumped\nauthors = ['me', \"you\"]\nedition = 2021\n\n[deps]\n\n[build]\nrelease = true\ncommand = \"make && <done>\"\n", t)
+	value, err := GetDataPath(edited, "toml", "package.authors")
+	if err != nil {
+		t.Fatalf("getting path: %v", err)
+	}
+	Assert(value, []interface{}{"me", "you"}, t)
+	value, err = GetDataPath(edited, "toml", "name")
+	if err != nil {
+		t.Fatalf("getting path: %v", err)
+	}
+	Assert(value, "app", t)
+	if _, err := GetDataPath(edited, "toml", "package.authors[0]"); err == nil {
+		t.Errorf("indexes should not be supported in TOML")
+	}
+}
+
+func TestEditDataTOMLMultiline(t *testing.T) {
+	content := []byte("[project]\ndescription = \"\"\"\nversion = 9\n[fake]\n\"\"\"\ndependencies = [\n    \"requests\",\n    [\"nested\"],\n]\nversion = \"1.0\"\n")
+	edited, err := EditData(content, "toml", []DataEdit{
+		{Path: "project.version", Value: "1.1"},
+		{Path: "project.name", Value: "app"},
+	})
+	if err != nil {
+		t.Fatalf("editing TOML: %v", err)
+	}
+	Assert(string(edited), "[project]\ndescription = \"\"\"\nversion = 9\n[fake]\n\"\"\"\ndependencies = [\n    \"requests\",\n    [\"nested\"],\n]\nversion = \"1.1\"\nname = \"app\"\n", t)
+	for _, path := range []string{"project.dependencies", "project.description"} {
+		if _, err := EditData(content, "toml", []DataEdit{{Path: path, Unset: true}}); err == nil {
+			t.Errorf("unsetting multi-line value '%s' should fail", path)
+		}
+		if _, err := EditData(content, "toml", []DataEdit{{Path: path, Value: "foo"}}); err == nil {
+			t.Errorf("setting multi-line value '%s' should fail", path)
+		}
+	}
+	if _, err := GetDataPath(content, "toml", "fake.version"); err == nil {
+		t.Errorf("keys in multi-line strings should be ignored")
+	}
+}
+
+func TestEditDataTOMLTables(t *testing.T) {
+	// tables defined with dotted keys or arrays of tables can't be extended
+	for content, path := range map[string]string{
+		"package.name = \"x\"\n":          "package.version",
+		"[tool]\npackage.name = \"x\"\n":  "tool.package.version",
+		"[[bin]]\nname = \"x\"\n":         "bin.version",
+		"[[package.bin]]\nname = \"x\"\n": "package.bin.test.version",
+	} {
+		if _, err := EditData([]byte(content), "toml", []DataEdit{{Path: path, Value: "1.0"}}); err == nil {
+			t.Errorf("setting '%s' in '%s' should fail", path, content)
+		}
+	}
+	// keys may still be added in root and other tables
+	content := []byte("name = \"x\"\n[[bin]]\nname = \"y\"\n[package]\nname = \"z\"\n")
+	edited, err := EditData(content, "toml", []DataEdit{
+		{Path: "version", Value: "1.0"},
+		{Path: "package.version", Value: "2.0"},
+	})
+	if err != nil {
+		t.Fatalf("editing TOML: %v", err)
+	}
+	Assert(string(edited), "name = \"x\"\nversion = \"1.0\"\n[[bin]]\nname = \"y\"\n[package]\nname = \"z\"\nversion = \"2.0\"\n", t)
+}
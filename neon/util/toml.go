@@ -0,0 +1,475 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TOML files are edited line by line to keep their formatting. Only keys in
+// tables with values on a single line are supported.
+
+// regexp for bare keys in TOML
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlLine is a line of a TOML file with its parsed path
+type tomlLine struct {
+	// table is the path of the table for a header, nil otherwise
+	table []string
+	// array tells if the header is one of an array of tables
+	array bool
+	// key is the full path of the key for a key line, nil otherwise
+	key []string
+	// index of the start of the value for a key line
+	value int
+	// index of the last line of the value for a key line
+	end int
+}
+
+func tomlGet(content []byte, elements []pathElement) (interface{}, error) {
+	path, err := tomlPath(elements)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	parsed, err := parseTomlLines(lines)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range parsed {
+		if equalPaths(line.key, path) {
+			return parseTomlValue(lines[i][line.value:])
+		}
+	}
+	return nil, fmt.Errorf("key '%s' not found", strings.Join(path, "."))
+}
+
+func tomlEdit(content []byte, edits []DataEdit) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+	for _, edit := range edits {
+		elements, err := parseDataPath(edit.Path)
+		if err != nil {
+			return nil, err
+		}
+		path, err := tomlPath(elements)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseTomlLines(lines)
+		if err != nil {
+			return nil, err
+		}
+		found := -1
+		for i, line := range parsed {
+			if equalPaths(line.key, path) {
+				found = i
+				break
+			}
+		}
+		if edit.Unset {
+			if found >= 0 {
+				if _, err := parseTomlValue(lines[found][parsed[found].value:]); err != nil {
+					return nil, fmt.Errorf("unsetting path '%s': %v", edit.Path, err)
+				}
+				lines = append(lines[:found], lines[found+1:]...)
+			}
+			continue
+		}
+		value, err := formatTomlValue(edit.Value)
+		if err != nil {
+			return nil, fmt.Errorf("editing path '%s': %v", edit.Path, err)
+		}
+		if found >= 0 {
+			line := lines[found]
+			start := parsed[found].value
+			if _, err := parseTomlValue(line[start:]); err != nil {
+				return nil, fmt.Errorf("editing path '%s': %v", edit.Path, err)
+			}
+			lines[found] = line[:start] + value + tomlComment(line[start:])
+			continue
+		}
+		lines, err = tomlInsert(lines, parsed, path, value)
+		if err != nil {
+			return nil, fmt.Errorf("editing path '%s': %v", edit.Path, err)
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// tomlInsert inserts a new key in its table, creating the table if needed.
+// Tables defined with dotted keys or arrays of tables can't be extended.
+func tomlInsert(lines []string, parsed []tomlLine, path []string, value string) ([]string, error) {
+	table := path[:len(path)-1]
+	key := formatTomlKey(path[len(path)-1:])
+	// look for the table and the last key line in it
+	current := []string{}
+	insert := -1
+	for i, line := range parsed {
+		if line.array {
+			for length := 1; length <= len(table); length++ {
+				if equalPaths(line.table, table[:length]) {
+					return nil, fmt.Errorf("table '%s' is in an array of tables", formatTomlKey(table))
+				}
+			}
+			current = nil
+			continue
+		}
+		if line.table != nil {
+			current = line.table
+			if equalPaths(current, table) {
+				insert = i + 1
+			}
+			continue
+		}
+		if len(table) > 0 && len(line.key) > len(table) && equalPaths(line.key[:len(table)], table) &&
+			!equalPaths(current, table) {
+			return nil, fmt.Errorf("table '%s' is defined with dotted keys", formatTomlKey(table))
+		}
+		if equalPaths(current, table) && line.key != nil {
+			insert = line.end + 1
+		}
+	}
+	if insert < 0 && len(table) == 0 {
+		insert = 0
+	}
+	if insert < 0 {
+		// append a new table at the end of the file
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		return append(lines, "["+formatTomlKey(table)+"]", key+" = "+value, ""), nil
+	}
+	result := append([]string{}, lines[:insert]...)
+	result = append(result, key+" = "+value)
+	return append(result, lines[insert:]...), nil
+}
+
+func tomlPath(elements []pathElement) ([]string, error) {
+	var path []string
+	for _, element := range elements {
+		if element.isIndex {
+			return nil, fmt.Errorf("indexes are not supported in TOML paths")
+		}
+		path = append(path, element.key)
+	}
+	return path, nil
+}
+
+func parseTomlLines(lines []string) ([]tomlLine, error) {
+	parsed := make([]tomlLine, len(lines))
+	var table []string
+	// state of a multi-line value: depth of arrays and open string delimiter
+	depth, quote, key := 0, "", 0
+	for i, line := range lines {
+		if depth > 0 || quote != "" {
+			// continuation of a multi-line value
+			depth, quote = tomlScan(line, depth, quote)
+			parsed[key].end = i
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[[") {
+			end := strings.Index(text, "]]")
+			if end < 0 {
+				return nil, fmt.Errorf("bad array of tables header on line %d", i+1)
+			}
+			keys, err := parseTomlKey(text[2:end])
+			if err != nil {
+				return nil, fmt.Errorf("bad array of tables header on line %d: %v", i+1, err)
+			}
+			parsed[i].table = keys
+			parsed[i].array = true
+			// keys of arrays of tables are not supported and can't be edited
+			table = []string{text}
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			end := strings.Index(text, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("bad table header on line %d", i+1)
+			}
+			keys, err := parseTomlKey(text[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("bad table header on line %d: %v", i+1, err)
+			}
+			table = keys
+			parsed[i].table = table
+			continue
+		}
+		equal := strings.Index(line, "=")
+		if equal < 0 {
+			// continuation of a multi-line value
+			continue
+		}
+		keys, err := parseTomlKey(line[:equal])
+		if err != nil {
+			// continuation of a multi-line value
+			continue
+		}
+		start := equal + 1
+		for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+			start++
+		}
+		parsed[i].key = append(append([]string{}, table...), keys...)
+		parsed[i].value = start
+		parsed[i].end = i
+		depth, quote = tomlScan(line[start:], 0, "")
+		if depth > 0 || quote != "" {
+			key = i
+		}
+	}
+	return parsed, nil
+}
+
+// parseTomlKey parses a dotted key such as 'a."b.c".d'
+func parseTomlKey(text string) ([]string, error) {
+	var keys []string
+	text = strings.TrimSpace(text)
+	for text != "" {
+		var key string
+		if text[0] == '"' || text[0] == '\'' {
+			end := strings.IndexByte(text[1:], text[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted key")
+			}
+			key = text[1 : end+1]
+			text = strings.TrimSpace(text[end+2:])
+		} else {
+			end := strings.IndexByte(text, '.')
+			if end < 0 {
+				end = len(text)
+			}
+			key = strings.TrimSpace(text[:end])
+			if !tomlBareKey.MatchString(key) {
+				return nil, fmt.Errorf("bad key '%s'", key)
+			}
+			text = text[end:]
+		}
+		keys = append(keys, key)
+		if text != "" {
+			if text[0] != '.' {
+				return nil, fmt.Errorf("bad key")
+			}
+			text = strings.TrimSpace(text[1:])
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	return keys, nil
+}
+
+// tomlScan scans a line of a value to find if it continues on next line:
+// - text: the text of the line
+// - depth: depth of arrays and inline tables at the start of the line
+// - quote: delimiter of multi-line string open at the start of the line
+// Return: depth and open multi-line string delimiter at the end of the line
+func tomlScan(text string, depth int, quote string) (int, string) {
+	for i := 0; i < len(text); i++ {
+		if quote != "" {
+			end := strings.Index(text[i:], quote)
+			if end < 0 {
+				return depth, quote
+			}
+			i += end + len(quote) - 1
+			quote = ""
+			continue
+		}
+		char := text[i]
+		switch {
+		case strings.HasPrefix(text[i:], `"""`) || strings.HasPrefix(text[i:], "'''"):
+			quote = text[i : i+3]
+			i += 2
+		case char == '"' || char == '\'':
+			for i++; i < len(text) && text[i] != char; i++ {
+				if char == '"' && text[i] == '\\' {
+					i++
+				}
+			}
+		case char == '[' || char == '{':
+			depth++
+		case char == ']' || char == '}':
+			depth--
+		case char == '#':
+			return depth, quote
+		}
+	}
+	return depth, quote
+}
+
+// tomlValueEnd returns the index of the end of the value at the start of text
+func tomlValueEnd(text string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		switch {
+		case quote != 0:
+			if char == '\\' && quote == '"' {
+				i++
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '[' || char == '{':
+			depth++
+		case char == ']' || char == '}':
+			depth--
+		case char == '#' && depth == 0:
+			return i
+		}
+	}
+	return len(text)
+}
+
+// tomlComment returns the comment at the end of a value, with its spacing
+func tomlComment(text string) string {
+	end := tomlValueEnd(text)
+	value := strings.TrimRight(text[:end], " \t")
+	return text[len(value):]
+}
+
+func parseTomlValue(text string) (interface{}, error) {
+	text = strings.TrimSpace(text[:tomlValueEnd(text)])
+	switch {
+	case strings.HasPrefix(text, `"""`) || strings.HasPrefix(text, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(text, `"`):
+		return strconv.Unquote(text)
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("bad literal string %s", text)
+		}
+		return text[1 : len(text)-1], nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("multi-line arrays are not supported")
+		}
+		list := []interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		for inner != "" {
+			end := tomlItemEnd(inner)
+			item, err := parseTomlValue(inner[:end])
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			inner = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(inner[end:]), ","))
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, fmt.Errorf("inline tables are not supported")
+	case text == "true":
+		return true, nil
+	case text == "false":
+		return false, nil
+	}
+	number := strings.ReplaceAll(text, "_", "")
+	if integer, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return integer, nil
+	}
+	if float, err := strconv.ParseFloat(number, 64); err == nil {
+		return float, nil
+	}
+	// dates and times are returned as strings
+	return text, nil
+}
+
+// tomlItemEnd returns the end of first item in an array
+func tomlItemEnd(text string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		switch {
+		case quote != 0:
+			if char == '\\' && quote == '"' {
+				i++
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '[' || char == '{':
+			depth++
+		case char == ']' || char == '}':
+			depth--
+		case char == ',' && depth == 0:
+			return i
+		}
+	}
+	return len(text)
+}
+
+func formatTomlKey(keys []string) string {
+	var formatted []string
+	for _, key := range keys {
+		if tomlBareKey.MatchString(key) {
+			formatted = append(formatted, key)
+		} else {
+			formatted = append(formatted, strconv.Quote(key))
+		}
+	}
+	return strings.Join(formatted, ".")
+}
+
+func formatTomlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		// HTML characters are not escaped to keep strings readable
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buffer.String(), "\n"), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		formatted := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(formatted, ".") {
+			formatted += ".0"
+		}
+		return formatted, nil
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			formatted, err := formatTomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, formatted)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case []string:
+		return formatTomlValue(orderedData(v))
+	}
+	return "", fmt.Errorf("values of type %T are not supported in TOML", value)
+}
+
+func equalPaths(first, second []string) bool {
+	if first == nil || len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
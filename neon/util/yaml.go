@@ -0,0 +1,172 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML files are edited as trees of nodes to keep their comments and style
+// of values. Lists in mappings are written back indented.
+
+// regexp to guess indentation of YAML files
+var yamlIndent = regexp.MustCompile(`\n( +)\S`)
+
+func yamlEdit(content []byte, edits []DataEdit) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("decoding YAML: %v", err)
+	}
+	if len(document.Content) == 0 {
+		// empty file
+		document = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	for _, edit := range edits {
+		elements, err := parseDataPath(edit.Path)
+		if err != nil {
+			return nil, err
+		}
+		if edit.Unset {
+			err = yamlUnset(document.Content[0], elements)
+		} else {
+			var value yaml.Node
+			if err = value.Encode(edit.Value); err == nil {
+				document.Content[0], err = yamlSet(document.Content[0], elements, &value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("editing path '%s': %v", edit.Path, err)
+		}
+	}
+	indent := 2
+	if match := yamlIndent.FindSubmatch(content); match != nil && len(match[1]) > indent {
+		indent = len(match[1])
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// yamlSet sets value at path in node, creating missing nodes, and returns
+// the modified node. Comments and style of replaced values are kept.
+func yamlSet(node *yaml.Node, elements []pathElement, value *yaml.Node) (*yaml.Node, error) {
+	if len(elements) == 0 {
+		if node != nil {
+			if node.Kind == value.Kind && (node.Kind != yaml.ScalarNode || node.Tag == value.Tag) {
+				value.Style = node.Style
+			}
+			value.HeadComment = node.HeadComment
+			value.LineComment = node.LineComment
+			value.FootComment = node.FootComment
+		}
+		return value, nil
+	}
+	element := elements[0]
+	if node != nil && node.Kind == yaml.AliasNode {
+		return nil, fmt.Errorf("value at %s is an alias", element)
+	}
+	if node != nil && node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		// null values are replaced with a list or a map
+		node = &yaml.Node{HeadComment: node.HeadComment, LineComment: node.LineComment,
+			FootComment: node.FootComment}
+	}
+	if element.isIndex {
+		if node == nil || node.Kind == 0 {
+			node = yamlNewNode(node, yaml.SequenceNode, "!!seq")
+		}
+		if node.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("value at %s is not a list", element)
+		}
+		if element.index < len(node.Content) {
+			child, err := yamlSet(node.Content[element.index], elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content[element.index] = child
+		} else if element.index == len(node.Content) {
+			child, err := yamlSet(nil, elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		} else {
+			return nil, fmt.Errorf("index %s out of range", element)
+		}
+		return node, nil
+	}
+	if node == nil || node.Kind == 0 {
+		node = yamlNewNode(node, yaml.MappingNode, "!!map")
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("value at %s is not a map", element)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == element.key {
+			child, err := yamlSet(node.Content[i+1], elements[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content[i+1] = child
+			return node, nil
+		}
+	}
+	child, err := yamlSet(nil, elements[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element.key}
+	node.Content = append(node.Content, key, child)
+	return node, nil
+}
+
+// yamlNewNode returns a new list or map, with comments of replaced node
+func yamlNewNode(node *yaml.Node, kind yaml.Kind, tag string) *yaml.Node {
+	created := &yaml.Node{Kind: kind, Tag: tag}
+	if node != nil {
+		created.HeadComment = node.HeadComment
+		created.LineComment = node.LineComment
+		created.FootComment = node.FootComment
+	}
+	return created
+}
+
+// yamlUnset removes value at path, doing nothing if path is not found
+func yamlUnset(node *yaml.Node, elements []pathElement) error {
+	element := elements[0]
+	last := len(elements) == 1
+	if element.isIndex {
+		if node.Kind != yaml.SequenceNode || element.index >= len(node.Content) {
+			return nil
+		}
+		if last {
+			node.Content = append(node.Content[:element.index], node.Content[element.index+1:]...)
+			return nil
+		}
+		return yamlUnset(node.Content[element.index], elements[1:])
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == element.key {
+			if last {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+				return nil
+			}
+			return yamlUnset(node.Content[i+1], elements[1:])
+		}
+	}
+	return nil
+}